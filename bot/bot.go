@@ -0,0 +1,219 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/chat"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Minute
+	// DefaultStateMaxAge is the max age of the MemoryStore New creates, flows with a longer
+	// timeout need a store of their own.
+	DefaultStateMaxAge = 24 * time.Hour
+)
+
+// Bot routes chat messages to handlers. A message is handled by the first match of: a command,
+// the current step of the user's flow, a prefix or regular expression route in registration
+// order, then the default handler. Commands therefore also work in the middle of a flow.
+type Bot struct {
+	sender   Sender
+	store    StateStore
+	options  Options
+	commands map[string]HandlerFunc
+	routes   []route
+	flows    map[string]Flow
+	fallback HandlerFunc
+	locks    [64]sync.Mutex
+}
+
+type route struct {
+	match   func(text string) ([]string, bool)
+	handler HandlerFunc
+}
+
+// New creates a bot replying through sender, store keeps conversation state and defaults to a
+// MemoryStore when nil. The default store drops states idle for DefaultStateMaxAge, unless flows
+// never expire.
+func New(sender Sender, store StateStore, options Options) *Bot {
+	if store == nil {
+		ms := NewMemoryStore()
+		if options.Timeout >= 0 {
+			ms.SetMaxAge(DefaultStateMaxAge)
+		}
+		store = ms
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	return &Bot{
+		sender:   sender,
+		store:    store,
+		options:  options,
+		commands: map[string]HandlerFunc{},
+		flows:    map[string]Flow{},
+	}
+}
+
+// Command handles messages whose first word is the command, such as "/leave 3 days", matched
+// without case. Args are the remaining words.
+func (b *Bot) Command(name string, h HandlerFunc) {
+	b.commands[strings.ToLower(name)] = h
+}
+
+// Prefix handles messages starting with prefix, Args holds the rest of the text.
+func (b *Bot) Prefix(prefix string, h HandlerFunc) {
+	b.routes = append(b.routes, route{
+		match: func(text string) ([]string, bool) {
+			if !strings.HasPrefix(text, prefix) {
+				return nil, false
+			}
+			return []string{strings.TrimSpace(text[len(prefix):])}, true
+		},
+		handler: h,
+	})
+}
+
+// Regexp handles messages matching re, Args holds the submatches.
+func (b *Bot) Regexp(re *regexp.Regexp, h HandlerFunc) {
+	b.routes = append(b.routes, route{
+		match: func(text string) ([]string, bool) {
+			m := re.FindStringSubmatch(text)
+			if m == nil {
+				return nil, false
+			}
+			return m[1:], true
+		},
+		handler: h,
+	})
+}
+
+// Default handles messages nothing else matched.
+func (b *Bot) Default(h HandlerFunc) {
+	b.fallback = h
+}
+
+func (b *Bot) AddFlow(f Flow) error {
+	if f.Name == "" {
+		return errors.New("flow name required")
+	}
+	if _, ok := f.Steps[f.Start]; !ok {
+		return errors.New(fmt.Sprintf("flow %s has no start step %q", f.Name, f.Start))
+	}
+	b.flows[f.Name] = f
+	return nil
+}
+
+// Register makes the bot handle the text, quick reply and postback events of a webhook handler.
+func (b *Bot) Register(h *chat.WebhookHandler) {
+	h.OnText(func(ctx context.Context, e chat.TextEvent) error {
+		return b.handle(&Context{Context: ctx, Event: e.Event, Text: e.Text})
+	})
+	h.OnQuickReply(func(ctx context.Context, e chat.QuickReplyEvent) error {
+		return b.handle(&Context{Context: ctx, Event: e.Event, Text: e.Text, Payload: e.Payload})
+	})
+	h.OnPostback(func(ctx context.Context, e chat.PostbackEvent) error {
+		return b.handle(&Context{Context: ctx, Event: e.Event, Text: e.Label, Payload: e.Payload})
+	})
+}
+
+func (b *Bot) handle(c *Context) error {
+	c.bot = b
+	c.Text = strings.TrimSpace(c.Text)
+	key := stateKey(c.Event)
+	lock := b.lock(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	state, err := b.store.Load(key)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		if f, ok := b.flows[state.Flow]; ok && b.expired(f, state) {
+			if err := b.store.Delete(key); err != nil {
+				return err
+			}
+			if f.OnTimeout != nil {
+				expired := *c
+				expired.state = state
+				if err := f.OnTimeout(&expired); err != nil {
+					return err
+				}
+			}
+			state = nil
+		}
+	}
+	c.state = state
+
+	h := b.route(c)
+	if h == nil {
+		return nil
+	}
+	if err := h(c); err != nil {
+		return err
+	}
+	if !c.changed {
+		if c.state == nil {
+			return nil
+		}
+		// keep the flow alive while the user is talking to it
+		c.state.UpdatedAt = time.Now()
+	}
+	if c.state == nil {
+		return b.store.Delete(key)
+	}
+	return b.store.Save(key, *c.state)
+}
+
+func (b *Bot) route(c *Context) HandlerFunc {
+	if fields := strings.Fields(c.Text); len(fields) > 0 {
+		if h, ok := b.commands[strings.ToLower(fields[0])]; ok {
+			c.Args = fields[1:]
+			return h
+		}
+	}
+	if c.state != nil {
+		if f, ok := b.flows[c.state.Flow]; ok {
+			if h, ok := f.Steps[c.state.Step]; ok {
+				return h
+			}
+		}
+		// the flow or step is gone, drop the stale state
+		c.state = nil
+		c.changed = true
+	}
+	for _, r := range b.routes {
+		if args, ok := r.match(c.Text); ok {
+			c.Args = args
+			return r.handler
+		}
+	}
+	return b.fallback
+}
+
+func (b *Bot) expired(f Flow, s *State) bool {
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = b.options.Timeout
+	}
+	return timeout > 0 && time.Since(s.UpdatedAt) > timeout
+}
+
+// lock serializes the messages of one user so their state is not updated concurrently.
+func (b *Bot) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &b.locks[h.Sum32()%uint32(len(b.locks))]
+}
+
+// stateKey separates the conversations of a user with each bot and in each group.
+func stateKey(e chat.Event) string {
+	return strings.Join([]string{e.BotId, e.GroupId, e.Source.UserId}, ":")
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/chat"
+	"time"
+)
+
+// Reply sends a text message back to the group room the event came from, or to the user.
+func (c *Context) Reply(text string) error {
+	return c.send(chat.Text(text))
+}
+
+// ReplyQuickReply sends a text message with quick reply choices back to the group room the event
+// came from, or to the user.
+func (c *Context) ReplyQuickReply(text string, choices []chat.QuickReply) error {
+	return c.send(chat.QuickReplies(text, choices...))
+}
+
+func (c *Context) send(msg chat.Message) error {
+	if c.Event.GroupId != "" {
+		return c.bot.sender.PushGroup(c, c.Event.GroupId, msg)
+	}
+	return c.bot.sender.Push(c, c.Event.Source.UserId, msg)
+}
+
+// StartFlow starts a flow at its start step, replacing any flow in progress. The step handles the
+// next message of the user.
+func (c *Context) StartFlow(name string) error {
+	f, ok := c.bot.flows[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("unknown flow %s", name))
+	}
+	c.state = &State{Flow: name, Step: f.Start, Data: map[string]string{}, UpdatedAt: time.Now()}
+	c.changed = true
+	return nil
+}
+
+// Goto moves the current flow to another step.
+func (c *Context) Goto(step string) error {
+	if c.state == nil {
+		return errors.New("no flow in progress")
+	}
+	if _, ok := c.bot.flows[c.state.Flow].Steps[step]; !ok {
+		return errors.New(fmt.Sprintf("flow %s has no step %q", c.state.Flow, step))
+	}
+	c.state.Step = step
+	c.state.UpdatedAt = time.Now()
+	c.changed = true
+	return nil
+}
+
+// EndFlow ends the current flow and drops its data.
+func (c *Context) EndFlow() {
+	c.state = nil
+	c.changed = true
+}
+
+// Flow returns the name and step of the flow in progress, empty when there is none.
+func (c *Context) Flow() (string, string) {
+	if c.state == nil {
+		return "", ""
+	}
+	return c.state.Flow, c.state.Step
+}
+
+// Get reads a value saved in the flow in progress.
+func (c *Context) Get(key string) string {
+	if c.state == nil {
+		return ""
+	}
+	return c.state.Data[key]
+}
+
+// Set saves a value in the flow in progress, it is ignored outside a flow.
+func (c *Context) Set(key string, value string) {
+	if c.state == nil {
+		return
+	}
+	if c.state.Data == nil {
+		c.state.Data = map[string]string{}
+	}
+	c.state.Data[key] = value
+	c.changed = true
+}
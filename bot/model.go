@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/inetspa/oneplatform-sdk-go/chat"
+	"time"
+)
+
+type HandlerFunc func(c *Context) error
+
+// Sender delivers replies to users and group rooms, *chat.Client implements it.
+type Sender interface {
+	Push(ctx context.Context, to string, msg chat.Message, opts ...chat.PushOption) error
+	PushGroup(ctx context.Context, groupId string, msg chat.Message, opts ...chat.PushOption) error
+}
+
+type Options struct {
+	// Timeout ends a conversation flow after that long without a message from the user, zero
+	// means DefaultTimeout and a negative timeout never expires flows.
+	Timeout time.Duration
+}
+
+// Flow is a multi-step conversation. Each step handles the next message of the user and moves
+// the conversation with Context.Goto or Context.EndFlow.
+type Flow struct {
+	Name  string
+	Start string
+	Steps map[string]HandlerFunc
+	// Timeout overrides Options.Timeout for this flow.
+	Timeout time.Duration
+	// OnTimeout is called with the expired state before the message is routed again.
+	OnTimeout HandlerFunc
+}
+
+// State is the conversation state of one user, stored between messages.
+type State struct {
+	Flow      string            `json:"flow"`
+	Step      string            `json:"step"`
+	Data      map[string]string `json:"data,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Context is passed to handlers, it carries the request context. Text is the message text or the
+// label of a postback, Payload the data of a quick reply or postback and Args the command
+// arguments or regular expression submatches.
+type Context struct {
+	context.Context
+	Event   chat.Event
+	Text    string
+	Args    []string
+	Payload json.RawMessage
+	bot     *Bot
+	state   *State
+	changed bool
+}
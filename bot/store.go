@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateStore keeps conversation state by user, Load returns nil without error when there is none.
+type StateStore interface {
+	Load(key string) (*State, error)
+	Save(key string, s State) error
+	Delete(key string) error
+}
+
+// MemoryStore keeps states in memory. Flows only expire when the same user writes again, so
+// without a max age abandoned conversations are kept forever, see SetMaxAge.
+type MemoryStore struct {
+	mu       sync.Mutex
+	states   map[string]State
+	maxAge   time.Duration
+	prunedAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+// SetMaxAge drops states not updated for longer than maxAge, checked on Load and Save. Dropped
+// states are gone without the flow's OnTimeout being called. Zero keeps states forever.
+func (m *MemoryStore) SetMaxAge(maxAge time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxAge = maxAge
+}
+
+func (m *MemoryStore) Load(key string) (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	s, ok := m.states[key]
+	if !ok || m.stale(s) {
+		return nil, nil
+	}
+	s.Data = copyData(s.Data)
+	return &s, nil
+}
+
+func (m *MemoryStore) Save(key string, s State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	s.Data = copyData(s.Data)
+	m.states[key] = s
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, key)
+	return nil
+}
+
+// prune drops stale states, at most every quarter of the max age so a busy bot does not scan
+// the map on every message.
+func (m *MemoryStore) prune() {
+	if m.maxAge <= 0 || time.Since(m.prunedAt) < m.maxAge/4 {
+		return
+	}
+	for key, s := range m.states {
+		if m.stale(s) {
+			delete(m.states, key)
+		}
+	}
+	m.prunedAt = time.Now()
+}
+
+func (m *MemoryStore) stale(s State) bool {
+	return m.maxAge > 0 && time.Since(s.UpdatedAt) > m.maxAge
+}
+
+// FileStore keeps each state in a JSON file of a directory, so conversations survive a restart.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) Load(key string) (*State, error) {
+	b, err := ioutil.ReadFile(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes to a temporary file first so a crash never leaves a partial state behind.
+func (f *FileStore) Save(key string, s State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(f.dir, ".state-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(key))
+}
+
+func (f *FileStore) Delete(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path hashes the key since user ids are not safe file names.
+func (f *FileStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func copyData(data map[string]string) map[string]string {
+	if data == nil {
+		return nil
+	}
+	c := make(map[string]string, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Backend stores encoded values with a time to live. Implementations must be safe for concurrent use.
+type Backend interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	DeletePrefix(prefix string) error
+}
+
+// Cache is a read-through cache on top of a Backend. Values are stored as json.
+type Cache struct {
+	backend Backend
+	group   Group
+}
+
+// New returns a cache on backend, a nil backend uses an in-memory LRU of DefaultLRUSize entries.
+func New(backend Backend) *Cache {
+	if backend == nil {
+		backend = NewLRU(DefaultLRUSize)
+	}
+	return &Cache{backend: backend}
+}
+
+// Fetch reads key into v. On a miss load is called once for all concurrent callers of the same key
+// and its result is stored for ttl. A result returned together with an error is decoded into v but
+// not stored. Backend failures are treated as misses.
+func (c *Cache) Fetch(key string, ttl time.Duration, v interface{}, load func() (interface{}, error)) error {
+	if b, ok, err := c.backend.Get(key); err == nil && ok {
+		if err := json.Unmarshal(b, v); err == nil {
+			return nil
+		}
+	}
+	b, err := c.group.Do(key, func() ([]byte, error) {
+		value, err := load()
+		b, merr := json.Marshal(value)
+		if merr != nil {
+			if err == nil {
+				err = merr
+			}
+			return nil, err
+		}
+		if err == nil {
+			_ = c.backend.Set(key, b, ttl)
+		}
+		return b, err
+	})
+	if b != nil {
+		if uerr := json.Unmarshal(b, v); uerr != nil && err == nil {
+			err = uerr
+		}
+	}
+	return err
+}
+
+func (c *Cache) Invalidate(key string) error {
+	return c.backend.Delete(key)
+}
+
+// InvalidatePrefix drops every key starting with prefix.
+func (c *Cache) InvalidatePrefix(prefix string) error {
+	return c.backend.DeletePrefix(prefix)
+}
@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const DefaultBroadcastWorkers = 8
+
+// Broadcast pushes the same message to many recipients over a pool of workers, going through the
+// client rate limiter. It returns one result per distinct recipient in the order given. When ctx
+// is cancelled the recipients not attempted yet are reported Pending together with ctx.Err(),
+// passing the results back in BroadcastOptions.Previous resumes the broadcast.
+// The message must not be a FileMessage since its body can only be read once.
+func (c *Client) Broadcast(ctx context.Context, recipients []string, msg Message, options BroadcastOptions) ([]DeliveryResult, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("%w: message required", ErrInvalidMessage)
+	}
+	if _, ok := msg.(*FileMessage); ok {
+		return nil, fmt.Errorf("%w: file messages cannot be broadcast", ErrInvalidMessage)
+	}
+	if err := c.validate(msg); err != nil {
+		return nil, err
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = DefaultBroadcastWorkers
+	}
+	previous := map[string]DeliveryResult{}
+	for _, r := range options.Previous {
+		previous[r.To] = r
+	}
+
+	var results []DeliveryResult
+	index := map[string]int{}
+	var todo []int
+	for _, to := range recipients {
+		to = strings.TrimSpace(to)
+		if _, ok := index[to]; ok || to == "" {
+			continue
+		}
+		index[to] = len(results)
+		if r, ok := previous[to]; ok && (r.Status == Delivered || r.Status == NotFriend) {
+			results = append(results, r)
+			continue
+		}
+		todo = append(todo, len(results))
+		results = append(results, DeliveryResult{To: to, Status: Pending})
+	}
+
+	var mu sync.Mutex
+	done := len(results) - len(todo)
+	finish := func(i int, r DeliveryResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = r
+		done++
+		if options.Progress != nil {
+			options.Progress(done, len(results), r)
+		}
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(todo); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				finish(i, c.deliver(ctx, results[i].To, msg, options))
+			}
+		}()
+	}
+dispatch:
+	for _, i := range todo {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		for i := range results {
+			if results[i].Status == Pending {
+				results[i].Err = err
+				results[i].Error = err.Error()
+			}
+		}
+		return results, err
+	}
+	return results, nil
+}
+
+func (c *Client) deliver(ctx context.Context, to string, msg Message, options BroadcastOptions) DeliveryResult {
+	r := DeliveryResult{To: to, Status: Delivered}
+	if options.CheckFriends {
+		friend, err := c.findChatFriend(ctx, to)
+		if err != nil {
+			r.Status, r.Err = Failed, err
+		} else if friend.UserId == "" {
+			r.Status = NotFriend
+		}
+	}
+	if r.Status == Delivered {
+		if err := c.Push(ctx, to, msg, options.PushOptions...); err != nil {
+			r.Status, r.Err = Failed, err
+		}
+	}
+	if r.Status == Failed && ctx.Err() != nil {
+		// cancelled before the recipient could be handled, a resumed run tries again
+		r.Status = Pending
+	}
+	if r.Err != nil {
+		r.Error = r.Err.Error()
+	}
+	return r
+}
@@ -2,29 +2,53 @@ package chat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/inetspa/golib/requests"
 	"github.com/inetspa/golib/web"
+	"github.com/inetspa/oneplatform-sdk-go/ratelimit"
 	"net/http"
+	"strings"
 )
 
 const (
-	apiEndpoint = "https://chat-api.one.th/message/api/v1"
+	apiEndpoint          = "https://chat-api.one.th/message/api/v1"
+	IdempotencyKeyHeader = "Idempotency-Key"
 )
 
+// DefaultTemplateLimits are the carousel limits NewClient checks: 10 cards, 3 choices per card,
+// 80 characters for a title, 120 for a detail and 20 for a choice label. TemplateMessage.Validate
+// also uses them. Change them per client with SetTemplateLimits when the platform does.
+var DefaultTemplateLimits = TemplateLimits{
+	Elements:     10,
+	Choices:      3,
+	TitleLength:  80,
+	DetailLength: 120,
+	LabelLength:  20,
+}
+
 func NewClient(botId string, token string, tokenType string) Client {
 	return Client{
 		botId:       botId,
 		token:       token,
 		tokenType:   tokenType,
 		apiEndpoint: apiEndpoint,
+		limits:      DefaultTemplateLimits,
 	}
 }
 
 func (c *Client) FindChatFriend(keyword string) (Friend, error) {
+	return c.findChatFriend(context.Background(), keyword)
+}
+
+// findChatFriend waits for the rate limiter, an empty friend means none was found.
+func (c *Client) findChatFriend(ctx context.Context, keyword string) (Friend, error) {
 	var friend Friend
+	if err := c.wait(ctx); err != nil {
+		return friend, err
+	}
 	msg := struct {
 		BotId   string `json:"bot_id"`
 		Keyword string `json:"key_search"`
@@ -35,7 +59,7 @@ func (c *Client) FindChatFriend(keyword string) (Friend, error) {
 	body, _ := json.Marshal(&msg)
 	r, err := c.send(http.MethodPost, c.url("/searchfriend"), body)
 	if err != nil {
-		return friend, nil
+		return friend, err
 	}
 	if r.Code != http.StatusOK {
 		return friend, errors.New(fmt.Sprintf("client return error with code %d (%s)", r.Code, string(r.Body)))
@@ -51,137 +75,115 @@ func (c *Client) FindChatFriend(keyword string) (Friend, error) {
 	return friend, nil
 }
 
-func (c *Client) PushTextMessage(to string, msg string, customNotify *string) error {
-	pushMessage := struct {
-		To           string `json:"to"`
-		BotId        string `json:"bot_id"`
-		Type         string `json:"type"`
-		Message      string `json:"message"`
-		CustomNotify string `json:"custom_notification,omitempty"`
-	}{
-		To:      to,
-		BotId:   c.botId,
-		Type:    "text",
-		Message: msg,
-	}
-	if customNotify != nil {
-		pushMessage.CustomNotify = *customNotify
+// Push validates and sends a message to a user.
+func (c *Client) Push(ctx context.Context, to string, msg Message, opts ...PushOption) error {
+	return c.push(ctx, "to", to, msg, opts)
+}
+
+// PushGroup validates and sends a message to a group room the bot belongs to.
+func (c *Client) PushGroup(ctx context.Context, groupId string, msg Message, opts ...PushOption) error {
+	return c.push(ctx, "group_id", groupId, msg, opts)
+}
+
+// push sends a message to the recipient named by field, "to" for a user or "group_id" for a group.
+func (c *Client) push(ctx context.Context, field string, to string, msg Message, opts []PushOption) error {
+	var o pushOptions
+	for _, opt := range opts {
+		opt(&o)
 	}
-	body, _ := json.Marshal(&pushMessage)
-	r, err := c.send(http.MethodPost, c.url("/push_message"), body)
+	pushMessage, err := c.prepare(field, to, msg, o)
 	if err != nil {
 		return err
 	}
-	if r.Code != http.StatusOK {
-		return errors.New(fmt.Sprintf("client return error with code %d (%s)", r.Code, string(r.Body)))
+	if err := c.wait(ctx); err != nil {
+		return err
 	}
-	return nil
+	if f, ok := msg.(*FileMessage); ok {
+		r, err := c.upload(c.url(msg.path()), pushMessage, f, o.progress)
+		if err != nil {
+			return err
+		}
+		return checkResponse(r)
+	}
+	return c.post(msg.path(), pushMessage, "")
 }
 
-func (c *Client) PushWebView(to string, label string, path string, img string, title string, detail string, customNotify *string) error {
-	pushMessage := struct {
-		To           string     `json:"to"`
-		BotId        string     `json:"bot_id"`
-		Type         string     `json:"type"`
-		CustomNotify string     `json:"custom_notification,omitempty"`
-		Elements     []Elements `json:"elements"`
-	}{
-		To:    to,
-		BotId: c.botId,
-		Type:  "template",
-		Elements: []Elements{
-			{
-				Image:  img,
-				Title:  title,
-				Detail: detail,
-				Choices: []Choice{
-					{
-						Label: label,
-						Type:  "webview",
-						Url:   path,
-						Size:  "full",
-					},
-				},
-			},
-		},
-	}
-
-	if customNotify != nil {
-		pushMessage.CustomNotify = *customNotify
-	}
-	body, _ := json.Marshal(&pushMessage)
-	r, err := c.send(http.MethodPost, c.url("/push_message"), body)
-	if err != nil {
-		return err
+// prepare validates a message and builds the body pushed for it.
+func (c *Client) prepare(field string, to string, msg Message, o pushOptions) (map[string]interface{}, error) {
+	if strings.TrimSpace(to) == "" {
+		return nil, fmt.Errorf("%w: recipient required", ErrInvalidMessage)
 	}
-	if r.Code != http.StatusOK {
-		return errors.New(fmt.Sprintf("server return error with http code %d : %s", r.Code, string(r.Body)))
+	if msg == nil {
+		return nil, fmt.Errorf("%w: message required", ErrInvalidMessage)
 	}
-	return nil
+	if err := c.validate(msg); err != nil {
+		return nil, err
+	}
+	pushMessage := msg.fields()
+	pushMessage[field] = to
+	pushMessage["bot_id"] = c.botId
+	if o.customNotify != "" {
+		pushMessage["custom_notification"] = o.customNotify
+	}
+	return pushMessage, nil
 }
 
-func (c *Client) PushLink(to string, label string, path string, img string, title string, detail string, customNotify *string) error {
-	pushMessage := struct {
-		To           string     `json:"to"`
-		BotId        string     `json:"bot_id"`
-		Type         string     `json:"type"`
-		CustomNotify string     `json:"custom_notification,omitempty"`
-		Elements     []Elements `json:"elements"`
-	}{
-		To:    to,
-		BotId: c.botId,
-		Type:  "template",
-		Elements: []Elements{
-			{
-				Image:  img,
-				Title:  title,
-				Detail: detail,
-				Choices: []Choice{
-					{
-						Label: label,
-						Type:  "link",
-						Url:   path,
-					},
-				},
-			},
-		},
-	}
-
-	if customNotify != nil {
-		pushMessage.CustomNotify = *customNotify
-	}
-	body, _ := json.Marshal(&pushMessage)
-	r, err := c.send(http.MethodPost, c.url("/push_message"), body)
-	if err != nil {
-		return err
+// validate checks a message, templates against the limits of the client instead of the defaults.
+func (c *Client) validate(msg Message) error {
+	if t, ok := msg.(*TemplateMessage); ok {
+		return t.validate(c.limits)
 	}
-	if r.Code != http.StatusOK {
-		return errors.New(fmt.Sprintf("server return error with http code %d : %s", r.Code, string(r.Body)))
+	return msg.Validate()
+}
+
+// wait blocks on the rate limiter, when one is set.
+func (c *Client) wait(ctx context.Context) error {
+	if c.limiter != nil {
+		return c.limiter.Wait(ctx)
 	}
-	return nil
+	return ctx.Err()
 }
 
-func (c *Client) PushQuickReply(to string, message string, quickReply []QuickReply) error {
-	pushQuickReply := struct {
-		To         string       `json:"to"`
-		BotId      string       `json:"bot_id"`
-		Message    string       `json:"message"`
-		QuickReply []QuickReply `json:"quick_reply"`
-	}{
-		To:         to,
-		BotId:      c.botId,
-		Message:    message,
-		QuickReply: quickReply,
+// post sends a JSON push, a non empty idempotencyKey is sent in the Idempotency-Key header.
+func (c *Client) post(path string, pushMessage map[string]interface{}, idempotencyKey string) error {
+	body, err := json.Marshal(pushMessage)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		web.HeaderContentType: web.MIMEApplicationJSON,
+	}
+	if idempotencyKey != "" {
+		headers[IdempotencyKeyHeader] = idempotencyKey
 	}
-	body, _ := json.Marshal(&pushQuickReply)
-	r, err := c.send(http.MethodPost, c.url("/push_quickreply"), body)
+	r, err := c.sendReader(http.MethodPost, c.url(path), headers, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
-	if r.Code != http.StatusOK {
-		return errors.New(fmt.Sprintf("server return error with http code %d : %s", r.Code, string(r.Body)))
+	return checkResponse(r)
+}
+
+// CustomNotification replaces the text shown in the push notification.
+func CustomNotification(text string) PushOption {
+	return func(o *pushOptions) {
+		o.customNotify = text
 	}
-	return nil
+}
+
+func (c *Client) PushTextMessage(to string, msg string, customNotify *string) error {
+	return c.pushUnchecked(to, Text(msg), customNotify)
+}
+
+func (c *Client) PushWebView(to string, label string, path string, img string, title string, detail string, customNotify *string) error {
+	return c.pushUnchecked(to, Template(Element(title, detail, img, WebViewChoice(label, path))), customNotify)
+}
+
+func (c *Client) PushLink(to string, label string, path string, img string, title string, detail string, customNotify *string) error {
+	return c.pushUnchecked(to, Template(Element(title, detail, img, LinkChoice(label, path))), customNotify)
+}
+
+func (c *Client) PushQuickReply(to string, message string, quickReply []QuickReply) error {
+	return c.pushUnchecked(to, QuickReplies(message, quickReply...), nil)
 }
 
 func (c *Client) GetChatProfile(oneChatToken string) (Profile, error) {
@@ -212,16 +214,60 @@ func (c *Client) SetEndpoint(ep string) {
 	c.apiEndpoint = ep
 }
 
+// SetTemplateLimits replaces DefaultTemplateLimits as the limits Push checks templates against, a
+// zero TemplateLimits disables the checks.
+func (c *Client) SetTemplateLimits(limits TemplateLimits) {
+	c.limits = limits
+}
+
+// SetRateLimiter makes every push wait for the limiter first, nil removes it.
+func (c *Client) SetRateLimiter(limiter ratelimit.Limiter) {
+	c.limiter = limiter
+}
+
 func (c *Client) send(method string, url string, body []byte) (requests.Response, error) {
-	headers := map[string]string{
-		web.HeaderContentType:   web.MIMEApplicationJSON,
-		web.HeaderAuthorization: fmt.Sprintf("%s %s", c.tokenType, c.token),
+	return c.sendReader(method, url, map[string]string{web.HeaderContentType: web.MIMEApplicationJSON}, bytes.NewBuffer(body))
+}
+
+// checkResponse turns a non 200 status or a fail status in the body into an ApiError.
+func checkResponse(r requests.Response) error {
+	if r.Code != http.StatusOK {
+		return &ApiError{StatusCode: r.Code, Message: string(r.Body)}
+	}
+	result := struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}{}
+	if err := json.Unmarshal(r.Body, &result); err == nil && strings.EqualFold(result.Status, "fail") {
+		return &ApiError{StatusCode: r.Code, Status: result.Status, Message: result.Message}
+	}
+	return nil
+}
+
+// pushUnchecked sends msg without validation and only fails on a non 200 status, as the
+// PushTextMessage family always did. New code should use Push.
+func (c *Client) pushUnchecked(to string, msg Message, customNotify *string) error {
+	pushMessage := msg.fields()
+	pushMessage["to"] = to
+	pushMessage["bot_id"] = c.botId
+	if customNotify != nil && *customNotify != "" {
+		pushMessage["custom_notification"] = *customNotify
+	}
+	if err := c.wait(context.Background()); err != nil {
+		return err
+	}
+	body, err := json.Marshal(pushMessage)
+	if err != nil {
+		return err
 	}
-	r, err := requests.Request(method, url, headers, bytes.NewBuffer(body), 0)
+	r, err := c.send(http.MethodPost, c.url(msg.path()), body)
 	if err != nil {
-		return r, err
+		return err
 	}
-	return r, nil
+	if r.Code != http.StatusOK {
+		return &ApiError{StatusCode: r.Code, Message: string(r.Body)}
+	}
+	return nil
 }
 
 func (c *Client) url(path string) string {
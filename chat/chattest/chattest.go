@@ -0,0 +1,215 @@
+// Package chattest provides a fake One Chat API server that records what clients push.
+package chattest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/chat"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+const (
+	BotId = "test-bot"
+	Token = "test-token"
+)
+
+// Server records every pushed message. Requests without "Bearer <Token>" get 401.
+type Server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	messages []Message
+	friends  []chat.Friend
+	groups   []group
+	failures []failure
+}
+
+type group struct {
+	chat.Group
+	members []chat.Friend
+}
+
+// Message is a recorded push, Fields holds the JSON body or the form fields of an upload. GroupId
+// is set instead of To for group pushes.
+type Message struct {
+	Path           string
+	IdempotencyKey string
+	To             string
+	GroupId        string
+	BotId          string
+	Type           string
+	Fields         map[string]interface{}
+	File           *File
+}
+
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type failure struct {
+	code int
+	body string
+}
+
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client of BotId talking to the server.
+func (s *Server) Client() chat.Client {
+	c := chat.NewClient(BotId, Token, "Bearer")
+	c.SetEndpoint(s.URL)
+	return c
+}
+
+// Messages returns the pushes received so far, oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+// Reset forgets received messages and pending failures.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.failures = nil
+}
+
+// AddFriend makes the friend searchable with FindChatFriend.
+func (s *Server) AddFriend(f chat.Friend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.friends = append(s.friends, f)
+}
+
+// AddGroup makes the group and its members listed by GetGroups and GetGroupMembers.
+func (s *Server) AddGroup(g chat.Group, members ...chat.Friend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups = append(s.groups, group{Group: g, members: members})
+}
+
+// FailNext answers the next request with the status code and body instead of handling it.
+func (s *Server) FailNext(code int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{code: code, body: body})
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		http.Error(w, `{"status":"fail","message":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mu.Unlock()
+		w.WriteHeader(f.code)
+		fmt.Fprint(w, f.body)
+		return
+	}
+	s.mu.Unlock()
+
+	m, err := readMessage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"status": "fail", "message": err.Error()})
+		return
+	}
+	switch m.Path {
+	case "/searchfriend":
+		s.searchFriend(w, m)
+	case "/group_list":
+		s.mu.Lock()
+		groups := []chat.Group{}
+		for _, g := range s.groups {
+			groups = append(groups, g.Group)
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "list_group": groups})
+	case "/group_member":
+		s.groupMembers(w, m)
+	case "/push_message", "/push_quickreply":
+		s.mu.Lock()
+		s.messages = append(s.messages, m)
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "success"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"status": "fail", "message": "not found"})
+	}
+}
+
+func (s *Server) searchFriend(w http.ResponseWriter, m Message) {
+	keyword := fmt.Sprint(m.Fields["key_search"])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.friends {
+		if f.OneEmail == keyword || f.UserId == keyword || f.AccountId == keyword {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "friend": f})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "fail", "message": "friend not found"})
+}
+
+func (s *Server) groupMembers(w http.ResponseWriter, m Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.groups {
+		if g.GroupId == m.GroupId {
+			members := append([]chat.Friend{}, g.members...)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "list_member": members})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "fail", "message": "group not found"})
+}
+
+func readMessage(r *http.Request) (Message, error) {
+	m := Message{Path: r.URL.Path, IdempotencyKey: r.Header.Get(chat.IdempotencyKeyHeader), Fields: map[string]interface{}{}}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(chat.MaxFileSize); err != nil {
+			return m, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			m.Fields[k] = strings.Join(v, ",")
+		}
+		if files := r.MultipartForm.File["file"]; len(files) > 0 {
+			f, err := files[0].Open()
+			if err != nil {
+				return m, err
+			}
+			defer f.Close()
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				return m, err
+			}
+			m.File = &File{Name: files[0].Filename, ContentType: files[0].Header.Get("Content-Type"), Data: data}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&m.Fields); err != nil {
+		return m, err
+	}
+	m.To, _ = m.Fields["to"].(string)
+	m.GroupId, _ = m.Fields["group_id"].(string)
+	m.BotId, _ = m.Fields["bot_id"].(string)
+	m.Type, _ = m.Fields["type"].(string)
+	return m, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
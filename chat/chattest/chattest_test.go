@@ -0,0 +1,132 @@
+package chattest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/inetspa/oneplatform-sdk-go/chat"
+	"github.com/inetspa/oneplatform-sdk-go/chat/chattest"
+	"io"
+	"testing"
+)
+
+func TestPushImage(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	data := bytes.Repeat([]byte{0xff}, 100<<10)
+	var sent, total int64
+	calls := 0
+	err := c.Push(context.Background(), "user-1", chat.Image("qr.png", "", bytes.NewReader(data)),
+		chat.UploadProgress(func(n int64, size int64) {
+			calls++
+			sent, total = n, size
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := s.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	m := messages[0]
+	if m.Path != "/push_message" || m.To != "user-1" || m.BotId != chattest.BotId || m.Type != "image" {
+		t.Fatalf("message = %+v, want an image to user-1 from the bot", m)
+	}
+	if m.File == nil || m.File.Name != "qr.png" || m.File.ContentType != "image/png" || !bytes.Equal(m.File.Data, data) {
+		t.Fatalf("file = %+v, want qr.png as image/png with the data sent", m.File)
+	}
+	if calls == 0 || sent != int64(len(data)) || total != int64(len(data)) {
+		t.Fatalf("progress called %d times ending at %d/%d, want %d/%d", calls, sent, total, len(data), len(data))
+	}
+}
+
+func TestPushFileToGroup(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	err := c.PushGroup(context.Background(), "group-1", chat.File("payslip.pdf", "", bytes.NewReader([]byte("%PDF-1.4"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := s.Messages()[0]
+	if m.GroupId != "group-1" || m.To != "" || m.Type != "file" {
+		t.Fatalf("message = %+v, want a file to group-1", m)
+	}
+	if m.File == nil || m.File.ContentType != "application/pdf" || string(m.File.Data) != "%PDF-1.4" {
+		t.Fatalf("file = %+v, want the pdf", m.File)
+	}
+}
+
+func TestPushImageTooLarge(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	body := bytes.NewReader(make([]byte, chat.MaxImageSize+1))
+	err := c.Push(context.Background(), "user-1", chat.Image("big.png", "", body))
+	if !errors.Is(err, chat.ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	if n := len(s.Messages()); n != 0 {
+		t.Fatalf("server received %d messages, want none", n)
+	}
+}
+
+// A body of unknown size is checked while it is uploaded.
+func TestPushImageTooLargeUnknownSize(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	body := io.LimitReader(zeros{}, chat.MaxImageSize+1)
+	var total int64
+	err := c.Push(context.Background(), "user-1", chat.Image("big.png", "", body),
+		chat.UploadProgress(func(n int64, size int64) { total = size }))
+	if !errors.Is(err, chat.ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+	if total != -1 {
+		t.Fatalf("progress total = %d, want -1 for an unknown size", total)
+	}
+	if n := len(s.Messages()); n != 0 {
+		t.Fatalf("server received %d messages, want none", n)
+	}
+}
+
+func TestPushImageWrongType(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	err := c.Push(context.Background(), "user-1", chat.Image("report.pdf", "", bytes.NewReader([]byte("x"))))
+	if !errors.Is(err, chat.ErrInvalidMessage) {
+		t.Fatalf("err = %v, want ErrInvalidMessage", err)
+	}
+}
+
+func TestPushSticker(t *testing.T) {
+	s := chattest.NewServer()
+	defer s.Close()
+	c := s.Client()
+
+	if err := c.Push(context.Background(), "user-1", chat.Sticker("42")); err != nil {
+		t.Fatal(err)
+	}
+	m := s.Messages()[0]
+	if m.Type != "sticker" || m.Fields["sticker_id"] != "42" {
+		t.Fatalf("message = %+v, want sticker 42", m)
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
@@ -0,0 +1,25 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// ErrCorruptEntry is wrapped by OutboxStore.List for stored entries it cannot read.
+var ErrCorruptEntry = errors.New("corrupt outbox entry")
+
+// ApiError is returned when the server answers with a non 200 status or a fail status.
+type ApiError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *ApiError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("server return code %d %s: %s", e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("server return code %d %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GetGroups lists the group rooms the bot is a member of.
+func (c *Client) GetGroups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	msg := struct {
+		BotId string `json:"bot_id"`
+	}{
+		BotId: c.botId,
+	}
+	result := struct {
+		Status string  `json:"status"`
+		Groups []Group `json:"list_group"`
+	}{}
+	if err := c.call(ctx, "/group_list", &msg, &result); err != nil {
+		return groups, err
+	}
+	return result.Groups, nil
+}
+
+// GetGroupMembers lists the users of a group room.
+func (c *Client) GetGroupMembers(ctx context.Context, groupId string) ([]Friend, error) {
+	var members []Friend
+	if strings.TrimSpace(groupId) == "" {
+		return members, fmt.Errorf("%w: group id required", ErrInvalidMessage)
+	}
+	msg := struct {
+		BotId   string `json:"bot_id"`
+		GroupId string `json:"group_id"`
+	}{
+		BotId:   c.botId,
+		GroupId: groupId,
+	}
+	result := struct {
+		Status  string   `json:"status"`
+		Members []Friend `json:"list_member"`
+	}{}
+	if err := c.call(ctx, "/group_member", &msg, &result); err != nil {
+		return members, err
+	}
+	return result.Members, nil
+}
+
+// call posts a JSON request through the rate limiter and decodes a successful answer into result.
+func (c *Client) call(ctx context.Context, path string, msg interface{}, result interface{}) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	r, err := c.send(http.MethodPost, c.url(path), body)
+	if err != nil {
+		return err
+	}
+	if err := checkResponse(r); err != nil {
+		return err
+	}
+	return json.Unmarshal(r.Body, result)
+}
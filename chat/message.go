@@ -0,0 +1,312 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+// Message is anything Client.Push can send, see Text, Template and QuickReplies.
+type Message interface {
+	// Validate reports why the platform would reject the message.
+	Validate() error
+	path() string
+	fields() map[string]interface{}
+}
+
+type TextMessage struct {
+	Text string
+}
+
+// TemplateMessage shows elements as cards, each with an image and buttons. Several elements are
+// shown as a carousel.
+type TemplateMessage struct {
+	Elements []Elements
+}
+
+// QuickReplyMessage is a text with choices the user can tap instead of typing.
+type QuickReplyMessage struct {
+	Text    string
+	Replies []QuickReply
+}
+
+// LocationMessage is a point on a map, the same type is received in a LocationEvent.
+type LocationMessage struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Title     string  `json:"title"`
+	Address   string  `json:"address"`
+}
+
+// ContactMessage is a contact card, the same type is received in a ContactEvent. It needs a phone
+// number or an email.
+type ContactMessage struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone,omitempty"`
+	Email        string `json:"email,omitempty"`
+	Organization string `json:"organization,omitempty"`
+}
+
+func Text(text string) *TextMessage {
+	return &TextMessage{Text: text}
+}
+
+func Template(elements ...Elements) *TemplateMessage {
+	return &TemplateMessage{Elements: elements}
+}
+
+func QuickReplies(text string, replies ...QuickReply) *QuickReplyMessage {
+	return &QuickReplyMessage{Text: text, Replies: replies}
+}
+
+func Location(latitude float64, longitude float64, title string, address string) *LocationMessage {
+	return &LocationMessage{Latitude: latitude, Longitude: longitude, Title: title, Address: address}
+}
+
+func Contact(name string, phone string, email string) *ContactMessage {
+	return &ContactMessage{Name: name, Phone: phone, Email: email}
+}
+
+// Element builds a template element, image may be empty.
+func Element(title string, detail string, image string, choices ...Choice) Elements {
+	return Elements{Image: image, Title: title, Detail: detail, Choices: choices}
+}
+
+// WebViewChoice opens url inside the chat in a full size web view.
+func WebViewChoice(label string, url string) Choice {
+	return Choice{Label: label, Type: "webview", Url: url, Size: "full"}
+}
+
+// LinkChoice opens url in the browser.
+func LinkChoice(label string, url string) Choice {
+	return Choice{Label: label, Type: "link", Url: url}
+}
+
+// PostbackChoice sends payload back to the bot as a PostbackEvent without posting a message.
+func PostbackChoice(label string, payload interface{}) Choice {
+	return Choice{Label: label, Type: "postback", Payload: payload}
+}
+
+// TextReply is a quick reply that sends message as the user, payload is passed back in the
+// QuickReplyEvent and may be nil.
+func TextReply(label string, message string, payload interface{}) QuickReply {
+	return QuickReply{Label: label, Type: "text", Message: message, Payload: payload}
+}
+
+// Add appends an element.
+func (m *TemplateMessage) Add(e Elements) *TemplateMessage {
+	m.Elements = append(m.Elements, e)
+	return m
+}
+
+// Add appends a reply.
+func (m *QuickReplyMessage) Add(r QuickReply) *QuickReplyMessage {
+	m.Replies = append(m.Replies, r)
+	return m
+}
+
+func (m *TextMessage) Validate() error {
+	if strings.TrimSpace(m.Text) == "" {
+		return fmt.Errorf("%w: text required", ErrInvalidMessage)
+	}
+	return nil
+}
+
+func (m *TextMessage) path() string {
+	return "/push_message"
+}
+
+func (m *TextMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "text",
+		"message": m.Text,
+	}
+}
+
+// Validate checks the template against DefaultTemplateLimits, Push uses the limits of the client.
+func (m *TemplateMessage) Validate() error {
+	return m.validate(DefaultTemplateLimits)
+}
+
+func (m *TemplateMessage) validate(limits TemplateLimits) error {
+	if len(m.Elements) == 0 {
+		return fmt.Errorf("%w: template needs an element", ErrInvalidMessage)
+	}
+	for i, e := range m.Elements {
+		if err := validateElement(e); err != nil {
+			return fmt.Errorf("%w: element %d: %v", ErrInvalidMessage, i, err)
+		}
+	}
+	return limits.check(m)
+}
+
+func (m *TemplateMessage) path() string {
+	return "/push_message"
+}
+
+func (m *TemplateMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "template",
+		"elements": m.Elements,
+	}
+}
+
+func (m *QuickReplyMessage) Validate() error {
+	if strings.TrimSpace(m.Text) == "" {
+		return fmt.Errorf("%w: text required", ErrInvalidMessage)
+	}
+	if len(m.Replies) == 0 {
+		return fmt.Errorf("%w: quick reply needs a choice", ErrInvalidMessage)
+	}
+	for i, r := range m.Replies {
+		if strings.TrimSpace(r.Label) == "" {
+			return fmt.Errorf("%w: reply %d: label required", ErrInvalidMessage, i)
+		}
+	}
+	return nil
+}
+
+func (m *QuickReplyMessage) path() string {
+	return "/push_quickreply"
+}
+
+func (m *QuickReplyMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"message":     m.Text,
+		"quick_reply": m.Replies,
+	}
+}
+
+func (m *LocationMessage) Validate() error {
+	if math.IsNaN(m.Latitude) || m.Latitude < -90 || m.Latitude > 90 {
+		return fmt.Errorf("%w: latitude %v out of range", ErrInvalidMessage, m.Latitude)
+	}
+	if math.IsNaN(m.Longitude) || m.Longitude < -180 || m.Longitude > 180 {
+		return fmt.Errorf("%w: longitude %v out of range", ErrInvalidMessage, m.Longitude)
+	}
+	if strings.TrimSpace(m.Title) == "" {
+		return fmt.Errorf("%w: location title required", ErrInvalidMessage)
+	}
+	return nil
+}
+
+func (m *LocationMessage) path() string {
+	return "/push_message"
+}
+
+func (m *LocationMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "location",
+		"latitude":  m.Latitude,
+		"longitude": m.Longitude,
+		"title":     m.Title,
+		"address":   m.Address,
+	}
+}
+
+func (m *ContactMessage) Validate() error {
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("%w: contact name required", ErrInvalidMessage)
+	}
+	if m.Phone == "" && m.Email == "" {
+		return fmt.Errorf("%w: contact needs a phone or an email", ErrInvalidMessage)
+	}
+	if m.Phone != "" && !validPhone(m.Phone) {
+		return fmt.Errorf("%w: invalid phone %q", ErrInvalidMessage, m.Phone)
+	}
+	if m.Email != "" {
+		if _, err := mail.ParseAddress(m.Email); err != nil {
+			return fmt.Errorf("%w: invalid email %q", ErrInvalidMessage, m.Email)
+		}
+	}
+	return nil
+}
+
+func (m *ContactMessage) path() string {
+	return "/push_message"
+}
+
+func (m *ContactMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "contact",
+		"contact": m,
+	}
+}
+
+// validPhone accepts digits with an optional leading plus and common separators.
+func validPhone(s string) bool {
+	digits := 0
+	for i, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '+' && i == 0:
+		case strings.ContainsRune("-(). ", c):
+		default:
+			return false
+		}
+	}
+	return digits >= 3 && digits <= 15
+}
+
+func validateElement(e Elements) error {
+	if strings.TrimSpace(e.Title) == "" {
+		return errors.New("title required")
+	}
+	for j, c := range e.Choices {
+		if err := validateChoice(c); err != nil {
+			return errors.New(fmt.Sprintf("choice %d: %v", j, err))
+		}
+	}
+	return nil
+}
+
+func validateChoice(c Choice) error {
+	if strings.TrimSpace(c.Label) == "" {
+		return errors.New("label required")
+	}
+	switch c.Type {
+	case "webview", "link":
+		if strings.TrimSpace(c.Url) == "" {
+			return errors.New(fmt.Sprintf("%s choice needs a url", c.Type))
+		}
+	case "postback":
+	default:
+		return errors.New(fmt.Sprintf("unknown choice type %q", c.Type))
+	}
+	return nil
+}
+
+// check applies the limits, see DefaultTemplateLimits and Client.SetTemplateLimits.
+func (l TemplateLimits) check(m *TemplateMessage) error {
+	if l.Elements > 0 && len(m.Elements) > l.Elements {
+		return fmt.Errorf("%w: template has %d elements, at most %d allowed", ErrInvalidMessage, len(m.Elements), l.Elements)
+	}
+	for i, e := range m.Elements {
+		if err := l.checkElement(e); err != nil {
+			return fmt.Errorf("%w: element %d: %v", ErrInvalidMessage, i, err)
+		}
+	}
+	return nil
+}
+
+func (l TemplateLimits) checkElement(e Elements) error {
+	if n := utf8.RuneCountInString(e.Title); l.TitleLength > 0 && n > l.TitleLength {
+		return errors.New(fmt.Sprintf("title has %d characters, at most %d allowed", n, l.TitleLength))
+	}
+	if n := utf8.RuneCountInString(e.Detail); l.DetailLength > 0 && n > l.DetailLength {
+		return errors.New(fmt.Sprintf("detail has %d characters, at most %d allowed", n, l.DetailLength))
+	}
+	if l.Choices > 0 && len(e.Choices) > l.Choices {
+		return errors.New(fmt.Sprintf("%d choices, at most %d allowed", len(e.Choices), l.Choices))
+	}
+	for j, c := range e.Choices {
+		if n := utf8.RuneCountInString(c.Label); l.LabelLength > 0 && n > l.LabelLength {
+			return errors.New(fmt.Sprintf("choice %d: label has %d characters, at most %d allowed", j, n, l.LabelLength))
+		}
+	}
+	return nil
+}
@@ -1,10 +1,28 @@
 package chat
 
+import (
+	"encoding/json"
+	"github.com/inetspa/oneplatform-sdk-go/ratelimit"
+	"time"
+)
+
 type Client struct {
 	botId       string
 	token       string
 	tokenType   string
 	apiEndpoint string
+	limiter     ratelimit.Limiter
+	limits      TemplateLimits
+}
+
+// TemplateLimits are checked by Push before a template is sent, a zero value disables a check.
+// Lengths are counted in characters.
+type TemplateLimits struct {
+	Elements     int
+	Choices      int
+	TitleLength  int
+	DetailLength int
+	LabelLength  int
 }
 
 type Profile struct {
@@ -22,11 +40,19 @@ type Friend struct {
 	Type        string `json:"type"`
 }
 
+type Group struct {
+	GroupId     string `json:"group_id"`
+	Name        string `json:"group_name"`
+	Picture     string `json:"group_picture"`
+	MemberCount int    `json:"member_count"`
+}
+
 type Choice struct {
-	Label string `json:"label"`
-	Type  string `json:"type"`
-	Url   string `json:"url"`
-	Size  string `json:"size"`
+	Label   string      `json:"label"`
+	Type    string      `json:"type"`
+	Url     string      `json:"url"`
+	Size    string      `json:"size"`
+	Payload interface{} `json:"payload,omitempty"`
 }
 type Elements struct {
 	Image   string   `json:"image"`
@@ -41,3 +67,200 @@ type QuickReply struct {
 	Message string      `json:"message"`
 	Payload interface{} `json:"payload"`
 }
+
+type EventType string
+
+const (
+	EventMessage      EventType = "message"
+	EventPostback     EventType = "postback"
+	EventAddFriend    EventType = "add_friend"
+	EventRemoveFriend EventType = "remove_friend"
+	EventBotJoined    EventType = "bot_join_group"
+	EventBotLeft      EventType = "bot_leave_group"
+	EventMemberJoined EventType = "member_join_group"
+	EventMemberLeft   EventType = "member_leave_group"
+)
+
+// Source is the user an inbound event came from
+type Source struct {
+	UserId      string `json:"user_id"`
+	AccountId   string `json:"one_id"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+// Event holds the fields shared by every webhook event, Raw is the undecoded request body.
+type Event struct {
+	Type      EventType       `json:"event"`
+	Id        string          `json:"event_id"`
+	BotId     string          `json:"bot_id"`
+	GroupId   string          `json:"group_id,omitempty"`
+	Source    Source          `json:"source"`
+	Timestamp int64           `json:"timestamp"`
+	Raw       json.RawMessage `json:"-"`
+}
+
+type TextEvent struct {
+	Event
+	MessageId string
+	Text      string
+}
+
+// QuickReplyEvent is sent when a user taps a quick reply, Payload is the payload of the choice.
+type QuickReplyEvent struct {
+	Event
+	MessageId string
+	Text      string
+	Payload   json.RawMessage
+}
+
+type PostbackEvent struct {
+	Event
+	Label   string
+	Payload json.RawMessage
+}
+
+// FileEvent is an image or file sent by a user, FileType is "image" or "file".
+type FileEvent struct {
+	Event
+	MessageId string
+	FileType  string
+	Url       string
+	Name      string
+	Size      int64
+}
+
+// LocationEvent is a location shared by a user.
+type LocationEvent struct {
+	Event
+	MessageId string
+	Location  LocationMessage
+}
+
+// ContactEvent is a contact card shared by a user.
+type ContactEvent struct {
+	Event
+	MessageId string
+	Contact   ContactMessage
+}
+
+// FriendEvent is sent when a user adds or removes the bot, see Event.Type.
+type FriendEvent struct {
+	Event
+}
+
+// GroupEvent is sent when the bot is added to or removed from a group, or when members join or
+// leave one, see Event.Type. Members lists the users who joined or left.
+type GroupEvent struct {
+	Event
+	Group   Group
+	Members []Friend
+}
+
+// UnknownEvent is any event or message type this package does not decode.
+type UnknownEvent struct {
+	Event
+}
+
+type VerifyOptions struct {
+	// Token is the bot token expected in the Authorization header.
+	Token string
+	// Secret is the key of the HMAC-SHA256 body signature sent in SignatureHeader.
+	Secret          string
+	SignatureHeader string
+	// AllowedAddresses are source IPs or CIDR ranges, empty allows any address.
+	AllowedAddresses []string
+	// TrustForwardedFor checks the address added by our own reverse proxy, the rightmost
+	// X-Forwarded-For entry, instead of the peer address. Only set it behind exactly one proxy
+	// that appends the client address.
+	TrustForwardedFor bool
+	// ReplayWindow bounds the event timestamp and how long event ids are remembered, zero means
+	// DefaultReplayWindow and a negative window disables replay checks.
+	ReplayWindow time.Duration
+}
+
+type PushOption func(*pushOptions)
+
+type pushOptions struct {
+	customNotify string
+	progress     func(sent int64, total int64)
+}
+
+type DeliveryStatus string
+
+const (
+	Delivered DeliveryStatus = "delivered"
+	Failed    DeliveryStatus = "failed"
+	NotFriend DeliveryStatus = "not_friend"
+	// Pending recipients were not attempted because the broadcast was cancelled.
+	Pending DeliveryStatus = "pending"
+)
+
+// DeliveryResult is the outcome of a broadcast for one recipient. Error keeps the message of Err
+// so results can be saved and passed back to resume a broadcast.
+type DeliveryResult struct {
+	To     string         `json:"to"`
+	Status DeliveryStatus `json:"status"`
+	Err    error          `json:"-"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type BroadcastOptions struct {
+	// Workers is the number of concurrent pushes, zero means DefaultBroadcastWorkers.
+	Workers int
+	// CheckFriends looks every recipient up first and skips those that are not friends of the bot.
+	CheckFriends bool
+	// Previous are the results of an earlier run, recipients already delivered or skipped are
+	// not sent again.
+	Previous []DeliveryResult
+	// Progress is called after each recipient with the number of recipients done so far.
+	Progress    func(done int, total int, r DeliveryResult)
+	PushOptions []PushOption
+}
+
+type OutboxState string
+
+const (
+	OutboxPending OutboxState = "pending"
+	OutboxSent    OutboxState = "sent"
+	OutboxDead    OutboxState = "dead"
+)
+
+// OutboxEntry is a queued message with the body rendered at enqueue time, it is sent to the user
+// To or to the group room GroupId.
+type OutboxEntry struct {
+	Key         string                 `json:"key"`
+	State       OutboxState            `json:"state"`
+	To          string                 `json:"to,omitempty"`
+	GroupId     string                 `json:"group_id,omitempty"`
+	Path        string                 `json:"path"`
+	Payload     map[string]interface{} `json:"payload"`
+	Attempts    int                    `json:"attempts"`
+	NextAttempt time.Time              `json:"next_attempt"`
+	LastError   string                 `json:"last_error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+type OutboxOptions struct {
+	// Dir is where the default file store keeps the queue, used when no store is given.
+	Dir string
+	// MaxAttempts moves a message to the dead letters after that many failed sends.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	// PollInterval is how often the worker looks for messages due for a retry.
+	PollInterval time.Duration
+	// SentRetention is how long keys of sent messages are kept to reject duplicates.
+	SentRetention time.Duration
+}
+
+// OutboxStats counts queued messages, Sent and Failures count sends since the outbox was created.
+type OutboxStats struct {
+	Pending   int
+	Dead      int
+	Sent      int64
+	Failures  int64
+	LastError string
+}
@@ -0,0 +1,312 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultOutboxDir        = "chat-outbox"
+	DefaultOutboxAttempts   = 8
+	DefaultOutboxMinBackoff = time.Second
+	DefaultOutboxMaxBackoff = 5 * time.Minute
+	DefaultOutboxPoll       = time.Second
+	DefaultOutboxRetention  = 24 * time.Hour
+)
+
+// Outbox stores messages before sending them so they survive an API outage or a restart. Run
+// sends them in the background, retrying with exponential backoff and moving messages that keep
+// failing, or that the API rejects, to the dead letters. A message is enqueued once per key, keys
+// of sent messages are remembered for OutboxOptions.SentRetention.
+//
+// Delivery is at least once: a crash after a send succeeded but before it was recorded sends the
+// message again on restart. The key is sent in the Idempotency-Key header so a server that
+// supports it can drop the duplicate.
+type Outbox struct {
+	client   *Client
+	store    OutboxStore
+	options  OutboxOptions
+	mu       sync.Mutex
+	wake     chan struct{}
+	sent     int64
+	failures int64
+	lastErr  string
+}
+
+// NewOutbox creates an outbox sending through c, store defaults to a FileOutboxStore in options.Dir.
+func NewOutbox(c *Client, store OutboxStore, options OutboxOptions) (*Outbox, error) {
+	if options.Dir == "" {
+		options.Dir = DefaultOutboxDir
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultOutboxAttempts
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = DefaultOutboxMinBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultOutboxMaxBackoff
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultOutboxPoll
+	}
+	if options.SentRetention <= 0 {
+		options.SentRetention = DefaultOutboxRetention
+	}
+	if store == nil {
+		fs, err := NewFileOutboxStore(options.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store = fs
+	}
+	return &Outbox{
+		client:  c,
+		store:   store,
+		options: options,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Enqueue validates and stores a message to a user, it returns the key, generated when key is
+// empty. Enqueueing a key that is queued, dead or recently sent does nothing.
+func (o *Outbox) Enqueue(to string, msg Message, key string, opts ...PushOption) (string, error) {
+	return o.enqueue("to", to, msg, key, opts)
+}
+
+// EnqueueGroup is Enqueue for a group room the bot belongs to.
+func (o *Outbox) EnqueueGroup(groupId string, msg Message, key string, opts ...PushOption) (string, error) {
+	return o.enqueue("group_id", groupId, msg, key, opts)
+}
+
+// enqueue stores a message to the recipient named by field, as in Client.push.
+func (o *Outbox) enqueue(field string, to string, msg Message, key string, opts []PushOption) (string, error) {
+	if _, ok := msg.(*FileMessage); ok {
+		return "", fmt.Errorf("%w: file messages cannot be queued", ErrInvalidMessage)
+	}
+	var po pushOptions
+	for _, opt := range opts {
+		opt(&po)
+	}
+	payload, err := o.client.prepare(field, to, msg, po)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		key = uuid.NewV4().String()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, err := o.store.Get(key); err != nil {
+		return "", err
+	} else if e != nil {
+		return key, nil
+	}
+	now := time.Now()
+	e := OutboxEntry{
+		Key:         key,
+		State:       OutboxPending,
+		Path:        msg.path(),
+		Payload:     payload,
+		NextAttempt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if field == "group_id" {
+		e.GroupId = to
+	} else {
+		e.To = to
+	}
+	if err := o.store.Put(e); err != nil {
+		return "", err
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return key, nil
+}
+
+// Run sends queued messages until ctx is done, it returns ctx.Err().
+func (o *Outbox) Run(ctx context.Context) error {
+	t := time.NewTicker(o.options.PollInterval)
+	defer t.Stop()
+	for {
+		if err := o.flush(ctx); err != nil && ctx.Err() == nil {
+			o.mu.Lock()
+			o.lastErr = err.Error()
+			o.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Stats reads the queue depth from the store together with the send counters.
+func (o *Outbox) Stats() (OutboxStats, error) {
+	entries, err := o.list()
+	if err != nil {
+		return OutboxStats{}, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s := OutboxStats{Sent: o.sent, Failures: o.failures, LastError: o.lastErr}
+	for _, e := range entries {
+		switch e.State {
+		case OutboxPending:
+			s.Pending++
+		case OutboxDead:
+			s.Dead++
+		}
+	}
+	return s, nil
+}
+
+// DeadLetters lists the messages that will not be retried, oldest first.
+func (o *Outbox) DeadLetters() ([]OutboxEntry, error) {
+	entries, err := o.list()
+	if err != nil {
+		return nil, err
+	}
+	var dead []OutboxEntry
+	for _, e := range entries {
+		if e.State == OutboxDead {
+			dead = append(dead, e)
+		}
+	}
+	sortEntries(dead)
+	return dead, nil
+}
+
+// Retry moves a dead letter back to the queue with its attempts reset.
+func (o *Outbox) Retry(key string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, err := o.store.Get(key)
+	if err != nil {
+		return err
+	}
+	if e == nil || e.State != OutboxDead {
+		return errors.New(fmt.Sprintf("no dead letter %s", key))
+	}
+	e.State = OutboxPending
+	e.Attempts = 0
+	e.NextAttempt = time.Now()
+	e.UpdatedAt = e.NextAttempt
+	if err := o.store.Put(*e); err != nil {
+		return err
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// flush sends the messages that are due, oldest first, and drops expired sent keys.
+func (o *Outbox) flush(ctx context.Context) error {
+	entries, err := o.list()
+	if err != nil {
+		return err
+	}
+	sortEntries(entries)
+	now := time.Now()
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch {
+		case e.State == OutboxSent && now.Sub(e.UpdatedAt) > o.options.SentRetention:
+			if err := o.store.Delete(e.Key); err != nil {
+				return err
+			}
+		case e.State == OutboxPending && !e.NextAttempt.After(now):
+			if err := o.send(ctx, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// list reads the store, entries it cannot read are reported in the stats instead of stopping
+// the outbox.
+func (o *Outbox) list() ([]OutboxEntry, error) {
+	entries, err := o.store.List()
+	if errors.Is(err, ErrCorruptEntry) {
+		o.mu.Lock()
+		o.lastErr = err.Error()
+		o.mu.Unlock()
+		return entries, nil
+	}
+	return entries, err
+}
+
+// send makes one attempt, the returned error is a store failure or ctx being done.
+func (o *Outbox) send(ctx context.Context, e OutboxEntry) error {
+	if err := o.client.wait(ctx); err != nil {
+		return err
+	}
+	err := o.client.post(e.Path, e.Payload, e.Key)
+	now := time.Now()
+	e.UpdatedAt = now
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err == nil {
+		o.sent++
+		e.State = OutboxSent
+		e.LastError = ""
+		return o.store.Put(e)
+	}
+	o.failures++
+	o.lastErr = err.Error()
+	e.Attempts++
+	e.LastError = err.Error()
+	if rejected(err) || e.Attempts >= o.options.MaxAttempts {
+		e.State = OutboxDead
+	} else {
+		e.NextAttempt = now.Add(o.backoff(e.Attempts))
+	}
+	return o.store.Put(e)
+}
+
+// backoff doubles the delay with each attempt and adds up to a fifth of jitter.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.options.MinBackoff
+	for i := 1; i < attempts && d < o.options.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > o.options.MaxBackoff {
+		d = o.options.MaxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// rejected reports errors that a retry will not fix: a fail status or a 4xx other than 429.
+func rejected(err error) bool {
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Status != "" {
+		return true
+	}
+	return apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests
+}
+
+func sortEntries(entries []OutboxEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+}
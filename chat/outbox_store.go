@@ -0,0 +1,177 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// OutboxStore persists outbox entries by key, Get returns nil without error for an unknown key.
+// List may return the readable entries together with an error wrapping ErrCorruptEntry, the
+// outbox reports it and keeps sending the rest.
+type OutboxStore interface {
+	Get(key string) (*OutboxEntry, error)
+	Put(e OutboxEntry) error
+	Delete(key string) error
+	List() ([]OutboxEntry, error)
+}
+
+// MemoryOutboxStore keeps the queue in memory, it does not survive a restart.
+type MemoryOutboxStore struct {
+	mu      sync.Mutex
+	entries map[string]OutboxEntry
+}
+
+func NewMemoryOutboxStore() *MemoryOutboxStore {
+	return &MemoryOutboxStore{entries: map[string]OutboxEntry{}}
+}
+
+func (m *MemoryOutboxStore) Get(key string) (*OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (m *MemoryOutboxStore) Put(e OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[e.Key] = e
+	return nil
+}
+
+func (m *MemoryOutboxStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *MemoryOutboxStore) List() ([]OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := make([]OutboxEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// corruptSuffix is added to entry files that cannot be read, they are kept for inspection but no
+// longer listed.
+const corruptSuffix = ".corrupt"
+
+// FileOutboxStore keeps each entry in a JSON file of a directory, written through a temporary
+// file and a rename so a crash never leaves a partial entry. Files that still cannot be read are
+// moved aside by List.
+type FileOutboxStore struct {
+	dir string
+}
+
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileOutboxStore{dir: dir}, nil
+}
+
+func (f *FileOutboxStore) Get(key string) (*OutboxEntry, error) {
+	e, err := f.read(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return e, err
+}
+
+func (f *FileOutboxStore) Put(e OutboxEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(f.dir, ".entry-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(e.Key))
+}
+
+func (f *FileOutboxStore) Delete(key string) error {
+	if err := os.Remove(f.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *FileOutboxStore) List() ([]OutboxEntry, error) {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []OutboxEntry
+	var corrupt []string
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(f.dir, fi.Name())
+		e, err := f.read(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if errors.Is(err, ErrCorruptEntry) {
+			os.Rename(path, path+corruptSuffix)
+			corrupt = append(corrupt, fi.Name())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	if len(corrupt) > 0 {
+		return entries, fmt.Errorf("%w: moved aside %s", ErrCorruptEntry, strings.Join(corrupt, ", "))
+	}
+	return entries, nil
+}
+
+func (f *FileOutboxStore) read(path string) (*OutboxEntry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e OutboxEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrCorruptEntry, filepath.Base(path), err)
+	}
+	return &e, nil
+}
+
+// path hashes the key since keys are not safe file names.
+func (f *FileOutboxStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"fmt"
+	"github.com/inetspa/golib/requests"
+	"github.com/inetspa/golib/web"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Upload limits checked before and while a file is sent.
+const (
+	MaxImageSize = 10 << 20
+	MaxFileSize  = 50 << 20
+)
+
+// FileMessage uploads an image or a file from Body. Size may be left zero, it is then read from
+// Body when possible and enforced while uploading otherwise. Body is consumed, so a FileMessage
+// can only be pushed once.
+type FileMessage struct {
+	Type        string
+	Name        string
+	ContentType string
+	Body        io.Reader
+	Size        int64
+}
+
+type StickerMessage struct {
+	Id string
+}
+
+// Image uploads a picture shown inline, contentType is guessed from name when empty.
+func Image(name string, contentType string, body io.Reader) *FileMessage {
+	return &FileMessage{Type: "image", Name: name, ContentType: contentType, Body: body}
+}
+
+// File uploads a document the user can download, contentType is guessed from name when empty.
+func File(name string, contentType string, body io.Reader) *FileMessage {
+	return &FileMessage{Type: "file", Name: name, ContentType: contentType, Body: body}
+}
+
+func Sticker(id string) *StickerMessage {
+	return &StickerMessage{Id: id}
+}
+
+// UploadProgress is called as a file is sent with the bytes sent so far and the total, which is
+// -1 when the size is unknown.
+func UploadProgress(fn func(sent int64, total int64)) PushOption {
+	return func(o *pushOptions) {
+		o.progress = fn
+	}
+}
+
+func (m *FileMessage) Validate() error {
+	if m.Type != "image" && m.Type != "file" {
+		return fmt.Errorf("%w: unknown file type %q", ErrInvalidMessage, m.Type)
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		return fmt.Errorf("%w: file name required", ErrInvalidMessage)
+	}
+	if m.Body == nil {
+		return fmt.Errorf("%w: file body required", ErrInvalidMessage)
+	}
+	if m.Type == "image" && !strings.HasPrefix(m.contentType(), "image/") {
+		return fmt.Errorf("%w: %s is not an image", ErrInvalidMessage, m.contentType())
+	}
+	if size := m.size(); size > m.maxSize() {
+		return fmt.Errorf("%w: %s is %d bytes, at most %d allowed", ErrInvalidMessage, m.Name, size, m.maxSize())
+	}
+	return nil
+}
+
+func (m *FileMessage) path() string {
+	return "/push_message"
+}
+
+func (m *FileMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type": m.Type,
+	}
+}
+
+func (m *FileMessage) contentType() string {
+	if m.ContentType != "" {
+		return m.ContentType
+	}
+	if t := mime.TypeByExtension(filepath.Ext(m.Name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (m *FileMessage) maxSize() int64 {
+	if m.Type == "image" {
+		return MaxImageSize
+	}
+	return MaxFileSize
+}
+
+// size returns the size of the body, or -1 when it cannot be known before reading it.
+func (m *FileMessage) size() int64 {
+	if m.Size > 0 {
+		return m.Size
+	}
+	switch b := m.Body.(type) {
+	case interface{ Len() int }:
+		return int64(b.Len())
+	case *os.File:
+		if st, err := b.Stat(); err == nil && st.Mode().IsRegular() {
+			return st.Size()
+		}
+	}
+	return -1
+}
+
+func (m *StickerMessage) Validate() error {
+	if strings.TrimSpace(m.Id) == "" {
+		return fmt.Errorf("%w: sticker id required", ErrInvalidMessage)
+	}
+	return nil
+}
+
+func (m *StickerMessage) path() string {
+	return "/push_message"
+}
+
+func (m *StickerMessage) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "sticker",
+		"sticker_id": m.Id,
+	}
+}
+
+// upload streams the form fields and the file as multipart form data, stopping with an error
+// once the file grows past its size limit.
+func (c *Client) upload(url string, fields map[string]interface{}, m *FileMessage, progress func(sent int64, total int64)) (requests.Response, error) {
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeForm(w, fields, m, progress))
+	}()
+	r, err := c.sendReader(http.MethodPost, url, map[string]string{web.HeaderContentType: w.FormDataContentType()}, pr)
+	pr.Close()
+	return r, err
+}
+
+func writeForm(w *multipart.Writer, fields map[string]interface{}, m *FileMessage, progress func(sent int64, total int64)) error {
+	for k, v := range fields {
+		if err := w.WriteField(k, fmt.Sprint(v)); err != nil {
+			return err
+		}
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(m.Name)))
+	h.Set(web.HeaderContentType, m.contentType())
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	pw := &progressWriter{w: part, limit: m.maxSize(), total: m.size(), fn: progress}
+	if _, err := io.Copy(pw, m.Body); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+type progressWriter struct {
+	w     io.Writer
+	sent  int64
+	limit int64
+	total int64
+	fn    func(sent int64, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	if p.sent+int64(len(b)) > p.limit {
+		return 0, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidMessage, p.limit)
+	}
+	n, err := p.w.Write(b)
+	p.sent += int64(n)
+	if p.fn != nil {
+		p.fn(p.sent, p.total)
+	}
+	return n, err
+}
+
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
+
+// sendReader sends a body with the given headers and the authorization header.
+func (c *Client) sendReader(method string, url string, headers map[string]string, body io.Reader) (requests.Response, error) {
+	h := map[string]string{
+		web.HeaderAuthorization: fmt.Sprintf("%s %s", c.tokenType, c.token),
+	}
+	for k, v := range headers {
+		h[k] = v
+	}
+	return requests.Request(method, url, h, body, 0)
+}
@@ -0,0 +1,211 @@
+package chat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/inetspa/golib/web"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSignatureHeader = "X-OneChat-Signature"
+	DefaultReplayWindow    = 5 * time.Minute
+)
+
+var (
+	ErrInvalidToken      = errors.New("invalid webhook token")
+	ErrInvalidSignature  = errors.New("invalid webhook signature")
+	ErrAddressNotAllowed = errors.New("webhook source address not allowed")
+	ErrStaleEvent        = errors.New("webhook event outside replay window")
+	ErrReplayedEvent     = errors.New("webhook event already received")
+)
+
+// VerifyError describes a rejected webhook request, Err is one of the Err* values above.
+type VerifyError struct {
+	RemoteAddr string
+	EventId    string
+	Err        error
+}
+
+func (e *VerifyError) Error() string {
+	if e.EventId != "" {
+		return fmt.Sprintf("reject webhook from %s (event %s): %v", e.RemoteAddr, e.EventId, e.Err)
+	}
+	return fmt.Sprintf("reject webhook from %s: %v", e.RemoteAddr, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookVerifier checks that webhook requests come from the platform. Only the checks with a
+// configured value are applied, see VerifyOptions.
+type WebhookVerifier struct {
+	options   VerifyOptions
+	networks  []*net.IPNet
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewWebhookVerifier(options VerifyOptions) (*WebhookVerifier, error) {
+	if options.SignatureHeader == "" {
+		options.SignatureHeader = DefaultSignatureHeader
+	}
+	if options.ReplayWindow == 0 {
+		options.ReplayWindow = DefaultReplayWindow
+	}
+	v := &WebhookVerifier{options: options, seen: map[string]time.Time{}}
+	for _, a := range options.AllowedAddresses {
+		if !strings.Contains(a, "/") {
+			if strings.Contains(a, ":") {
+				a += "/128"
+			} else {
+				a += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			return nil, err
+		}
+		v.networks = append(v.networks, n)
+	}
+	return v, nil
+}
+
+// Verify checks the source address, the bot token, the body signature and the event timestamp and
+// id against replays, in that order. The id of an accepted event is remembered for the replay window.
+func (v *WebhookVerifier) Verify(r *http.Request, body []byte) error {
+	remote := remoteAddr(r, v.options.TrustForwardedFor)
+	reject := func(id string, err error) error {
+		return &VerifyError{RemoteAddr: remote, EventId: id, Err: err}
+	}
+	if len(v.networks) > 0 && !v.allowed(remote) {
+		return reject("", ErrAddressNotAllowed)
+	}
+	if v.options.Token != "" {
+		auth := r.Header.Get(web.HeaderAuthorization)
+		if i := strings.IndexByte(auth, ' '); i >= 0 {
+			auth = strings.TrimSpace(auth[i+1:])
+		}
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(v.options.Token)) != 1 {
+			return reject("", ErrInvalidToken)
+		}
+	}
+	if v.options.Secret != "" && !validSignature(r.Header.Get(v.options.SignatureHeader), v.options.Secret, body) {
+		return reject("", ErrInvalidSignature)
+	}
+	if v.options.ReplayWindow < 0 {
+		return nil
+	}
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		// malformed bodies are left to the handler
+		return nil
+	}
+	now := time.Now()
+	if e.Timestamp > 0 {
+		at := eventTime(e.Timestamp)
+		if at.Before(now.Add(-v.options.ReplayWindow)) || at.After(now.Add(v.options.ReplayWindow)) {
+			return reject(e.Id, ErrStaleEvent)
+		}
+	}
+	if e.Id != "" && !v.reserve(e.Id, now) {
+		return reject(e.Id, ErrReplayedEvent)
+	}
+	return nil
+}
+
+func (v *WebhookVerifier) allowed(remote string) bool {
+	ip := net.ParseIP(remote)
+	if ip == nil {
+		return false
+	}
+	for _, n := range v.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// reserve records an event id, it fails when the id was seen within the replay window.
+func (v *WebhookVerifier) reserve(id string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.lastPrune) > v.options.ReplayWindow/4 {
+		for k, t := range v.seen {
+			if now.Sub(t) > v.options.ReplayWindow {
+				delete(v.seen, k)
+			}
+		}
+		v.lastPrune = now
+	}
+	if t, ok := v.seen[id]; ok && now.Sub(t) <= v.options.ReplayWindow {
+		return false
+	}
+	v.seen[id] = now
+	return true
+}
+
+// forget releases an event id so a retry of an event that failed to be handled is accepted.
+func (v *WebhookVerifier) forget(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.seen, id)
+}
+
+// validSignature accepts a hex or base64 HMAC-SHA256 of the body, optionally prefixed with "sha256=".
+func validSignature(header string, secret string, body []byte) bool {
+	header = strings.TrimPrefix(strings.TrimSpace(header), "sha256=")
+	if header == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+	if got, err := hex.DecodeString(header); err == nil && hmac.Equal(got, sum) {
+		return true
+	}
+	if got, err := base64.StdEncoding.DecodeString(header); err == nil && hmac.Equal(got, sum) {
+		return true
+	}
+	return false
+}
+
+// eventTime reads a unix timestamp in seconds or milliseconds.
+func eventTime(ts int64) time.Time {
+	if ts > 1e12 {
+		return time.Unix(0, ts*int64(time.Millisecond))
+	}
+	return time.Unix(ts, 0)
+}
+
+// remoteAddr returns the peer address, or with trustForwardedFor the rightmost X-Forwarded-For
+// entry. Only that entry is written by the proxy in front of us, the ones left of it come from the
+// client and can be forged.
+func remoteAddr(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
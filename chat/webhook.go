@@ -0,0 +1,301 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// MaxWebhookBodySize is the largest request body a WebhookHandler accepts.
+const MaxWebhookBodySize = 1 << 20
+
+// WebhookHandler receives bot events from One Chat and passes them to the registered functions.
+// It answers 200 when the event was handled or has no handler, 400 for a body it cannot decode
+// and 500 when a handler returns an error so the platform may retry. Handlers must be registered
+// before the first request is served.
+type WebhookHandler struct {
+	onText         func(ctx context.Context, e TextEvent) error
+	onQuickReply   func(ctx context.Context, e QuickReplyEvent) error
+	onPostback     func(ctx context.Context, e PostbackEvent) error
+	onImage        func(ctx context.Context, e FileEvent) error
+	onFile         func(ctx context.Context, e FileEvent) error
+	onLocation     func(ctx context.Context, e LocationEvent) error
+	onContact      func(ctx context.Context, e ContactEvent) error
+	onAddFriend    func(ctx context.Context, e FriendEvent) error
+	onRemoveFriend func(ctx context.Context, e FriendEvent) error
+	onGroup        map[EventType]func(ctx context.Context, e GroupEvent) error
+	onUnknown      func(ctx context.Context, e UnknownEvent) error
+	verifier       *WebhookVerifier
+	onReject       func(r *http.Request, err error)
+}
+
+type webhookPayload struct {
+	Event
+	Message *struct {
+		Id       string          `json:"id"`
+		Type     string          `json:"type"`
+		Text     string          `json:"text"`
+		Data     json.RawMessage `json:"data"`
+		File     string          `json:"file"`
+		FileName string          `json:"file_name"`
+		FileSize int64           `json:"file_size"`
+		LocationMessage
+		Contact *ContactMessage `json:"contact"`
+	} `json:"message"`
+	Group    *Group   `json:"group"`
+	Members  []Friend `json:"members"`
+	Postback *struct {
+		Label string          `json:"label"`
+		Data  json.RawMessage `json:"data"`
+	} `json:"postback"`
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{}
+}
+
+func (h *WebhookHandler) OnText(fn func(ctx context.Context, e TextEvent) error) {
+	h.onText = fn
+}
+
+func (h *WebhookHandler) OnQuickReply(fn func(ctx context.Context, e QuickReplyEvent) error) {
+	h.onQuickReply = fn
+}
+
+func (h *WebhookHandler) OnPostback(fn func(ctx context.Context, e PostbackEvent) error) {
+	h.onPostback = fn
+}
+
+func (h *WebhookHandler) OnImage(fn func(ctx context.Context, e FileEvent) error) {
+	h.onImage = fn
+}
+
+func (h *WebhookHandler) OnFile(fn func(ctx context.Context, e FileEvent) error) {
+	h.onFile = fn
+}
+
+func (h *WebhookHandler) OnLocation(fn func(ctx context.Context, e LocationEvent) error) {
+	h.onLocation = fn
+}
+
+func (h *WebhookHandler) OnContact(fn func(ctx context.Context, e ContactEvent) error) {
+	h.onContact = fn
+}
+
+func (h *WebhookHandler) OnAddFriend(fn func(ctx context.Context, e FriendEvent) error) {
+	h.onAddFriend = fn
+}
+
+func (h *WebhookHandler) OnRemoveFriend(fn func(ctx context.Context, e FriendEvent) error) {
+	h.onRemoveFriend = fn
+}
+
+func (h *WebhookHandler) OnBotJoined(fn func(ctx context.Context, e GroupEvent) error) {
+	h.onGroupEvent(EventBotJoined, fn)
+}
+
+func (h *WebhookHandler) OnBotLeft(fn func(ctx context.Context, e GroupEvent) error) {
+	h.onGroupEvent(EventBotLeft, fn)
+}
+
+func (h *WebhookHandler) OnMemberJoined(fn func(ctx context.Context, e GroupEvent) error) {
+	h.onGroupEvent(EventMemberJoined, fn)
+}
+
+func (h *WebhookHandler) OnMemberLeft(fn func(ctx context.Context, e GroupEvent) error) {
+	h.onGroupEvent(EventMemberLeft, fn)
+}
+
+func (h *WebhookHandler) onGroupEvent(t EventType, fn func(ctx context.Context, e GroupEvent) error) {
+	if h.onGroup == nil {
+		h.onGroup = map[EventType]func(ctx context.Context, e GroupEvent) error{}
+	}
+	h.onGroup[t] = fn
+}
+
+// OnUnknown receives events and message types that have no typed form, with the raw body.
+func (h *WebhookHandler) OnUnknown(fn func(ctx context.Context, e UnknownEvent) error) {
+	h.onUnknown = fn
+}
+
+// SetVerifier makes the handler reject requests that fail verification, 401 for a bad token or
+// signature, 403 for a source address that is not allowed and 409 for stale or replayed events.
+func (h *WebhookHandler) SetVerifier(v *WebhookVerifier) {
+	h.verifier = v
+}
+
+// OnReject is called with the *VerifyError of every rejected request, typically to log it.
+func (h *WebhookHandler) OnReject(fn func(r *http.Request, err error)) {
+	h.onReject = fn
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxWebhookBodySize+1))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if len(body) > MaxWebhookBodySize {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if h.verifier != nil {
+		if err := h.verifier.Verify(r, body); err != nil {
+			if h.onReject != nil {
+				h.onReject(r, err)
+			}
+			http.Error(w, http.StatusText(rejectStatus(err)), rejectStatus(err))
+			return
+		}
+	}
+	e, err := ParseEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.Dispatch(r.Context(), e); err != nil {
+		if h.verifier != nil {
+			h.verifier.forget(eventId(e))
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Dispatch passes a parsed event to its registered function, events without one are ignored.
+func (h *WebhookHandler) Dispatch(ctx context.Context, e interface{}) error {
+	switch e := e.(type) {
+	case TextEvent:
+		if h.onText != nil {
+			return h.onText(ctx, e)
+		}
+	case QuickReplyEvent:
+		if h.onQuickReply != nil {
+			return h.onQuickReply(ctx, e)
+		}
+	case PostbackEvent:
+		if h.onPostback != nil {
+			return h.onPostback(ctx, e)
+		}
+	case FileEvent:
+		if e.FileType == "image" && h.onImage != nil {
+			return h.onImage(ctx, e)
+		}
+		if e.FileType == "file" && h.onFile != nil {
+			return h.onFile(ctx, e)
+		}
+	case LocationEvent:
+		if h.onLocation != nil {
+			return h.onLocation(ctx, e)
+		}
+	case ContactEvent:
+		if h.onContact != nil {
+			return h.onContact(ctx, e)
+		}
+	case FriendEvent:
+		if e.Type == EventAddFriend && h.onAddFriend != nil {
+			return h.onAddFriend(ctx, e)
+		}
+		if e.Type == EventRemoveFriend && h.onRemoveFriend != nil {
+			return h.onRemoveFriend(ctx, e)
+		}
+	case GroupEvent:
+		if fn := h.onGroup[e.Type]; fn != nil {
+			return fn(ctx, e)
+		}
+	case UnknownEvent:
+		if h.onUnknown != nil {
+			return h.onUnknown(ctx, e)
+		}
+	default:
+		return errors.New(fmt.Sprintf("unsupported event %T", e))
+	}
+	return nil
+}
+
+// ParseEvent decodes a webhook body into TextEvent, QuickReplyEvent, PostbackEvent, FileEvent,
+// LocationEvent, ContactEvent, FriendEvent, GroupEvent or UnknownEvent.
+func ParseEvent(body []byte) (interface{}, error) {
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+	if p.Type == "" {
+		return nil, errors.New("missing event type")
+	}
+	p.Raw = append(json.RawMessage{}, body...)
+	switch p.Type {
+	case EventMessage:
+		if p.Message == nil {
+			return nil, errors.New("message event without message")
+		}
+		m := p.Message
+		switch m.Type {
+		case "text", "":
+			if hasData(m.Data) {
+				return QuickReplyEvent{Event: p.Event, MessageId: m.Id, Text: m.Text, Payload: m.Data}, nil
+			}
+			return TextEvent{Event: p.Event, MessageId: m.Id, Text: m.Text}, nil
+		case "image", "file":
+			return FileEvent{Event: p.Event, MessageId: m.Id, FileType: m.Type, Url: m.File, Name: m.FileName, Size: m.FileSize}, nil
+		case "location":
+			return LocationEvent{Event: p.Event, MessageId: m.Id, Location: m.LocationMessage}, nil
+		case "contact":
+			if m.Contact != nil {
+				return ContactEvent{Event: p.Event, MessageId: m.Id, Contact: *m.Contact}, nil
+			}
+		}
+	case EventPostback:
+		if p.Postback != nil {
+			return PostbackEvent{Event: p.Event, Label: p.Postback.Label, Payload: p.Postback.Data}, nil
+		}
+	case EventAddFriend, EventRemoveFriend:
+		return FriendEvent{Event: p.Event}, nil
+	case EventBotJoined, EventBotLeft, EventMemberJoined, EventMemberLeft:
+		e := GroupEvent{Event: p.Event, Members: p.Members}
+		if p.Group != nil {
+			e.Group = *p.Group
+		}
+		if e.Group.GroupId == "" {
+			e.Group.GroupId = p.GroupId
+		}
+		return e, nil
+	}
+	return UnknownEvent{Event: p.Event}, nil
+}
+
+func rejectStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAddressNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, ErrStaleEvent), errors.Is(err, ErrReplayedEvent):
+		return http.StatusConflict
+	}
+	return http.StatusUnauthorized
+}
+
+// eventId reads the id of any of the typed events through their embedded Event.
+func eventId(e interface{}) string {
+	if b, ok := e.(interface{ event() Event }); ok {
+		return b.event().Id
+	}
+	return ""
+}
+
+func (e Event) event() Event {
+	return e
+}
+
+func hasData(data json.RawMessage) bool {
+	return len(data) > 0 && string(data) != "null" && string(data) != `""`
+}
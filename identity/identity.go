@@ -72,12 +72,21 @@ func (id *Identity) GetProfile(tokenType string, accessToken string) (AccountPro
 	if tokenType == "" || accessToken == "" {
 		return profile, errors.New("login required")
 	}
-	headers := id.headers
-	headers[web.HeaderAuthorization] = fmt.Sprintf("%s %s", tokenType, accessToken)
+	headers := map[string]string{
+		web.HeaderAuthorization: fmt.Sprintf("%s %s", tokenType, accessToken),
+	}
+	for k, v := range id.headers {
+		if _, ok := headers[k]; !ok {
+			headers[k] = v
+		}
+	}
 	r, err := id.send(http.MethodGet, id.url("/api/account"), nil, headers)
 	if err != nil {
 		return profile, err
 	}
+	if r.Code != http.StatusOK {
+		return profile, errors.New(fmt.Sprintf("client return error with code %d (%s)", r.Code, string(r.Body)))
+	}
 	return profile, json.Unmarshal(r.Body, &profile)
 }
 
@@ -0,0 +1,63 @@
+package identity
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/inetspa/golib/web"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const profileContextKey contextKey = iota
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenVerifier resolves a bearer token to the account it belongs to. Identity.GetProfile can be
+// used directly.
+type TokenVerifier func(tokenType string, accessToken string) (AccountProfile, error)
+
+// StaticToken accepts only the given shared token, as used by provisioning clients.
+// The profile passed on is empty.
+func StaticToken(token string) TokenVerifier {
+	return func(tokenType string, accessToken string) (AccountProfile, error) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(accessToken), []byte(token)) != 1 {
+			return AccountProfile{}, ErrInvalidToken
+		}
+		return AccountProfile{}, nil
+	}
+}
+
+// BearerAuth rejects requests without a valid "Authorization: Bearer" header with 401 and stores
+// the verified profile in the request context, see ProfileFromContext.
+func BearerAuth(verify TokenVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get(web.HeaderAuthorization)
+			i := strings.IndexByte(auth, ' ')
+			if i < 0 || !strings.EqualFold(auth[:i], "bearer") || strings.TrimSpace(auth[i+1:]) == "" {
+				unauthorized(w)
+				return
+			}
+			profile, err := verify(auth[:i], strings.TrimSpace(auth[i+1:]))
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), profileContextKey, profile)))
+		})
+	}
+}
+
+// ProfileFromContext returns the profile stored by BearerAuth.
+func ProfileFromContext(ctx context.Context) (AccountProfile, bool) {
+	profile, ok := ctx.Value(profileContextKey).(AccountProfile)
+	return profile, ok
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="oneplatform"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
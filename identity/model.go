@@ -1,6 +1,9 @@
 package identity
 
-import uuid "github.com/satori/go.uuid"
+import (
+	"encoding/json"
+	uuid "github.com/satori/go.uuid"
+)
 
 // Identity model struct
 type Identity struct {
@@ -113,6 +116,31 @@ type Employee struct {
 	EmployeeId string          `json:"employee_id"`
 	Account    *AccountProfile `json:"account"`
 	Employee   *Employee       `json:"employee"`
-	Position   string          `json:"position"`
-	PositionId uuid.UUID       `json:"role_id"`
+	Role       Role            `json:"role"`
+}
+
+// Role or position held by an employee
+type Role struct {
+	Id    uuid.UUID `json:"id"`
+	Name  string    `json:"role_name"`
+	Level int       `json:"role_level"`
+}
+
+// UnmarshalJSON also accepts the flat role_id field used by the business API
+// when the role object itself is not embedded.
+func (e *Employee) UnmarshalJSON(b []byte) error {
+	type employee Employee
+	aux := struct {
+		*employee
+		RoleId *uuid.UUID `json:"role_id"`
+	}{
+		employee: (*employee)(e),
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if aux.RoleId != nil && e.Role.Id == uuid.Nil {
+		e.Role.Id = *aux.RoleId
+	}
+	return nil
 }
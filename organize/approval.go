@@ -0,0 +1,238 @@
+package organize
+
+import (
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"sync"
+)
+
+// ApprovalResolver walks up the department tree to find who approves for an account.
+// Departments, heads and resolved chains are cached per business until Invalidate is called.
+type ApprovalResolver struct {
+	org     *OrgClient
+	options ApprovalOptions
+	mu      sync.Mutex
+	cache   map[string]*approvalCache
+}
+
+type approvalCache struct {
+	mu          sync.Mutex
+	departments map[uuid.UUID]Department
+	heads       map[uuid.UUID][]identity.Employee
+	chains      map[string][]Approver
+}
+
+func NewApprovalResolver(org *OrgClient, options ApprovalOptions) *ApprovalResolver {
+	return &ApprovalResolver{
+		org:     org,
+		options: options,
+		cache:   map[string]*approvalCache{},
+	}
+}
+
+// Resolve returns the ordered approval chain of an account, nearest head first. Vacant departments
+// and departments headed by the account itself or by someone already in the chain are skipped.
+// An account in several departments is resolved from the deepest one, ties go to the lowest id.
+func (r *ApprovalResolver) Resolve(taxNo string, accountId string) ([]Approver, error) {
+	bc, err := r.business(taxNo)
+	if err != nil {
+		return nil, err
+	}
+	if chain, ok := bc.chain(accountId); ok {
+		return append([]Approver{}, chain...), nil
+	}
+
+	var errs MultiError
+	headDepartments, err := r.org.GetHeadDepartmentAccounts(accountId, taxNo)
+	if err := errs.merge(err); err != nil {
+		return nil, err
+	}
+	bc.addHeads(headDepartments)
+	var chain []Approver
+	if len(headDepartments) == 0 {
+		return chain, errs.errOrNil()
+	}
+
+	seen := map[string]bool{accountId: true}
+	visited := map[uuid.UUID]bool{}
+	deptId := bc.deepest(headDepartments)
+	for deptId != uuid.Nil && !visited[deptId] {
+		if r.options.MaxDepth > 0 && len(chain) >= r.options.MaxDepth {
+			break
+		}
+		visited[deptId] = true
+		d := bc.department(deptId)
+		heads, err := r.headsOf(bc, taxNo, deptId)
+		if err := errs.merge(err); err != nil {
+			return chain, err
+		}
+		for _, h := range heads {
+			if !seen[h.AccountId] {
+				seen[h.AccountId] = true
+				chain = append(chain, Approver{Level: len(chain) + 1, Department: d, Employee: h})
+				break
+			}
+		}
+		if r.options.StopAt != nil && deptId == *r.options.StopAt {
+			break
+		}
+		deptId = parentOf(d)
+	}
+	if len(errs) == 0 {
+		bc.setChain(accountId, chain)
+	}
+	return append([]Approver{}, chain...), errs.errOrNil()
+}
+
+// Invalidate drops everything cached for a business.
+func (r *ApprovalResolver) Invalidate(taxNo string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cache, taxNo)
+}
+
+// business returns the cache of a business, loading its departments outside the lock the first
+// time. When two loads race the first one stored is kept.
+func (r *ApprovalResolver) business(taxNo string) (*approvalCache, error) {
+	r.mu.Lock()
+	bc, ok := r.cache[taxNo]
+	r.mu.Unlock()
+	if ok {
+		return bc, nil
+	}
+	departments, err := r.org.GetDepartments(taxNo)
+	if err != nil && !IsPartial(err) {
+		return nil, err
+	}
+	bc = &approvalCache{
+		departments: map[uuid.UUID]Department{},
+		heads:       map[uuid.UUID][]identity.Employee{},
+		chains:      map[string][]Approver{},
+	}
+	for _, d := range departments {
+		bc.departments[d.Id] = d
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.cache[taxNo]; ok {
+		return cached, nil
+	}
+	r.cache[taxNo] = bc
+	return bc, nil
+}
+
+// headsOf finds the heads of a department through the head-department data of its members. The
+// lookup stops at the first member whose response covers the department. A department no response
+// covers is vacant, which is only cached when every response decoded.
+func (r *ApprovalResolver) headsOf(bc *approvalCache, taxNo string, deptId uuid.UUID) ([]identity.Employee, error) {
+	if heads, ok := bc.headsOf(deptId); ok {
+		return heads, nil
+	}
+	var errs MultiError
+	members, err := r.org.GetDepartmentAccounts(taxNo, deptId)
+	if err := errs.merge(err); err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		headDepartments, err := r.org.GetHeadDepartmentAccounts(m.AccountId, taxNo)
+		if err := errs.merge(err); err != nil {
+			return nil, err
+		}
+		bc.addHeads(headDepartments)
+		if covers(headDepartments, deptId) {
+			break
+		}
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if _, ok := bc.heads[deptId]; !ok && len(errs) == 0 {
+		bc.heads[deptId] = nil
+	}
+	return bc.heads[deptId], errs.errOrNil()
+}
+
+func covers(headDepartments []HeadDepartment, deptId uuid.UUID) bool {
+	for _, hd := range headDepartments {
+		if hd.Id == deptId {
+			return true
+		}
+	}
+	return false
+}
+
+func (bc *approvalCache) addHeads(headDepartments []HeadDepartment) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	for _, hd := range headDepartments {
+		if _, ok := bc.departments[hd.Id]; !ok {
+			bc.departments[hd.Id] = Department{Id: hd.Id, Name: hd.Name, ParentDeptId: hd.ParentDeptId}
+		}
+		if hd.Accounts != nil {
+			bc.heads[hd.Id] = *hd.Accounts
+		} else if _, ok := bc.heads[hd.Id]; !ok {
+			bc.heads[hd.Id] = nil
+		}
+	}
+}
+
+// deepest returns the head department furthest from the root, or with the lowest id among equals.
+func (bc *approvalCache) deepest(headDepartments []HeadDepartment) uuid.UUID {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	deptId, depth := uuid.Nil, -1
+	for _, hd := range headDepartments {
+		d := bc.depth(hd.Id)
+		if d > depth || d == depth && hd.Id.String() < deptId.String() {
+			deptId, depth = hd.Id, d
+		}
+	}
+	return deptId
+}
+
+// depth counts the ancestors of a known department, the caller holds bc.mu.
+func (bc *approvalCache) depth(deptId uuid.UUID) int {
+	seen := map[uuid.UUID]bool{deptId: true}
+	n := 0
+	for {
+		d, ok := bc.departments[deptId]
+		if !ok {
+			return n
+		}
+		deptId = parentOf(d)
+		if deptId == uuid.Nil || seen[deptId] {
+			return n
+		}
+		seen[deptId] = true
+		n++
+	}
+}
+
+func (bc *approvalCache) headsOf(deptId uuid.UUID) ([]identity.Employee, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	heads, ok := bc.heads[deptId]
+	return heads, ok
+}
+
+// department returns a cached department, or one with only the id when it is unknown.
+func (bc *approvalCache) department(deptId uuid.UUID) Department {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if d, ok := bc.departments[deptId]; ok {
+		return d
+	}
+	return Department{Id: deptId}
+}
+
+func (bc *approvalCache) chain(accountId string) ([]Approver, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	chain, ok := bc.chains[accountId]
+	return chain, ok
+}
+
+func (bc *approvalCache) setChain(accountId string, chain []Approver) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.chains[accountId] = chain
+}
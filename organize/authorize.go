@@ -0,0 +1,141 @@
+package organize
+
+import (
+	"errors"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"strings"
+)
+
+// Authorizer answers membership and role questions for an account from the cached department
+// tree and account roles, so repeated checks do not reach the API until the cache expires.
+type Authorizer struct {
+	c *CachedClient
+}
+
+func NewAuthorizer(c *CachedClient) *Authorizer {
+	return &Authorizer{c: c}
+}
+
+// InDepartment reports whether the account is a member of the department or any of its children.
+func (a *Authorizer) InDepartment(taxNo string, accountId string, departmentUid uuid.UUID) (bool, error) {
+	return a.match(taxNo, accountId, &departmentUid, "")
+}
+
+// HasRole reports whether the account holds a role, given by id or name, in any department of the business.
+func (a *Authorizer) HasRole(taxNo string, accountId string, role string) (bool, error) {
+	return a.match(taxNo, accountId, nil, role)
+}
+
+// Allowed reports whether the account matches any rule of the policy. A rule with an invalid tax
+// number fails the whole check before any department is loaded.
+func (a *Authorizer) Allowed(accountId string, policy Policy) (bool, error) {
+	for _, rule := range policy.Rules {
+		if err := ValidateTaxNo(rule.TaxNo); err != nil {
+			return false, err
+		}
+	}
+	var errs MultiError
+	for _, rule := range policy.Rules {
+		ok, err := a.match(rule.TaxNo, accountId, rule.Department, rule.Role)
+		if err := errs.merge(err); err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, errs.errOrNil()
+}
+
+// Require only lets through requests from accounts allowed by the policy. It reads the profile
+// stored by identity.BearerAuth and answers 401 without one and 403 when the account is not allowed.
+// A policy with an invalid tax number answers 500.
+func (a *Authorizer) Require(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			profile, ok := identity.ProfileFromContext(r.Context())
+			if !ok || profile.ID == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			allowed, err := a.Allowed(profile.ID, policy)
+			if errors.Is(err, ErrInvalidTaxNo) {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if err != nil && !IsPartial(err) {
+				http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+				return
+			}
+			if !allowed {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// match looks for the account in the subtree of root, or in every department when root is nil,
+// holding the given role when one is set. It reads the roles of the account in one call, and the
+// department tree only when root is set.
+func (a *Authorizer) match(taxNo string, accountId string, root *uuid.UUID, role string) (bool, error) {
+	if err := ValidateTaxNo(taxNo); err != nil {
+		return false, err
+	}
+	var errs MultiError
+	roles, err := a.c.GetAccountRoles(accountId, taxNo)
+	if err := errs.merge(err); err != nil {
+		return false, err
+	}
+	var inTree map[uuid.UUID]bool
+	if root != nil && len(roles) > 0 {
+		departments, err := a.c.GetDepartments(taxNo)
+		if err := errs.merge(err); err != nil {
+			return false, err
+		}
+		inTree = map[uuid.UUID]bool{}
+		for _, id := range subtree(departments, *root) {
+			inTree[id] = true
+		}
+	}
+	for _, r := range roles {
+		if (inTree == nil || inTree[r.DepartmentId]) && roleMatches(r.Role, role) {
+			return true, nil
+		}
+	}
+	return false, errs.errOrNil()
+}
+
+// subtree lists root and all departments below it, root first.
+func subtree(departments []Department, root uuid.UUID) []uuid.UUID {
+	children := map[uuid.UUID][]uuid.UUID{}
+	for _, d := range departments {
+		if p := parentOf(d); p != uuid.Nil {
+			children[p] = append(children[p], d.Id)
+		}
+	}
+	ids := []uuid.UUID{root}
+	seen := map[uuid.UUID]bool{root: true}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range children[ids[i]] {
+			if !seen[c] {
+				seen[c] = true
+				ids = append(ids, c)
+			}
+		}
+	}
+	return ids
+}
+
+func roleMatches(r Role, role string) bool {
+	if role == "" {
+		return true
+	}
+	if id, err := uuid.FromString(role); err == nil {
+		return r.Id == id
+	}
+	return strings.EqualFold(strings.TrimSpace(r.Name), strings.TrimSpace(role))
+}
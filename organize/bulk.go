@@ -0,0 +1,91 @@
+package organize
+
+import (
+	"context"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"sync"
+)
+
+const DefaultBulkWorkers = 8
+
+// GetAllDepartmentAccounts loads the members of every department of a business using a pool of
+// workers. Requests go through the client rate limiter and stop when ctx is done. Departments that
+// failed are reported in a *BulkError, the members of the others are still returned. Departments
+// with malformed members keep the members that could be decoded.
+func (org *OrgClient) GetAllDepartmentAccounts(ctx context.Context, taxNo string, options BulkOptions) (map[uuid.UUID][]identity.Employee, error) {
+	departments, err := org.GetDepartments(taxNo)
+	if err != nil && !IsPartial(err) {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(departments))
+	for i, d := range departments {
+		ids[i] = d.Id
+	}
+	members, bulkErr := org.GetDepartmentsAccounts(ctx, taxNo, ids, options)
+	if bulkErr != nil {
+		return members, bulkErr
+	}
+	return members, err
+}
+
+// GetDepartmentsAccounts loads the members of the given departments, see GetAllDepartmentAccounts.
+func (org *OrgClient) GetDepartmentsAccounts(ctx context.Context, taxNo string, departmentUids []uuid.UUID, options BulkOptions) (map[uuid.UUID][]identity.Employee, error) {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = DefaultBulkWorkers
+	}
+	if workers > len(departmentUids) {
+		workers = len(departmentUids)
+	}
+	members := map[uuid.UUID][]identity.Employee{}
+	failures := map[uuid.UUID]error{}
+	var mu sync.Mutex
+	jobs := make(chan uuid.UUID)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				employees, err := org.getDepartmentAccounts(ctx, taxNo, id)
+				mu.Lock()
+				if err == nil || IsPartial(err) {
+					members[id] = employees
+				}
+				if err != nil {
+					failures[id] = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for i, id := range departmentUids {
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			mu.Lock()
+			for _, rest := range departmentUids[i:] {
+				failures[rest] = ctx.Err()
+			}
+			mu.Unlock()
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if len(failures) > 0 {
+		return members, &BulkError{Total: len(departmentUids), Failures: failures}
+	}
+	return members, nil
+}
+
+func (b *Business) GetAllDepartmentAccounts(ctx context.Context, options BulkOptions) (map[uuid.UUID][]identity.Employee, error) {
+	return b.org.GetAllDepartmentAccounts(ctx, b.taxNo, options)
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d departments failed", len(e.Failures), e.Total)
+}
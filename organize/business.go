@@ -0,0 +1,141 @@
+package organize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+)
+
+var ErrInvalidTaxNo = errors.New("invalid tax id")
+
+// ValidateTaxNo checks the 13 digit Thai tax id including its check digit.
+func ValidateTaxNo(taxNo string) error {
+	if len(taxNo) != 13 {
+		return fmt.Errorf("%w %q: must be 13 digits", ErrInvalidTaxNo, taxNo)
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if taxNo[i] < '0' || taxNo[i] > '9' {
+			return fmt.Errorf("%w %q: must be 13 digits", ErrInvalidTaxNo, taxNo)
+		}
+		if i < 12 {
+			sum += int(taxNo[i]-'0') * (13 - i)
+		}
+	}
+	if check := (11 - sum%11) % 10; check != int(taxNo[12]-'0') {
+		return fmt.Errorf("%w %q: check digit mismatch", ErrInvalidTaxNo, taxNo)
+	}
+	return nil
+}
+
+// GetBusinesses lists the businesses the authenticated account belongs to.
+func (org *OrgClient) GetBusinesses() ([]BusinessInfo, error) {
+	var businesses []BusinessInfo
+	data, err := org.get("/list", "")
+	if err != nil {
+		return businesses, err
+	}
+	err = decodeList("/list", data, func(i int, raw json.RawMessage) error {
+		var b BusinessInfo
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return err
+		}
+		businesses = append(businesses, b)
+		return nil
+	})
+	return businesses, err
+}
+
+// Business returns a handle bound to one tax id so it does not have to be passed on every call.
+func (org *OrgClient) Business(taxNo string) (*Business, error) {
+	if err := ValidateTaxNo(taxNo); err != nil {
+		return nil, err
+	}
+	return &Business{org: org, taxNo: taxNo}, nil
+}
+
+func (b *Business) TaxNo() string {
+	return b.taxNo
+}
+
+func (b *Business) GetAccounts() ([]identity.AccountProfile, error) {
+	return b.org.GetAccounts(b.taxNo)
+}
+
+func (b *Business) IterateAccounts(pageSize int) *AccountIterator {
+	return b.org.IterateAccounts(b.taxNo, pageSize)
+}
+
+func (b *Business) IterateDepartments(pageSize int) *DepartmentIterator {
+	return b.org.IterateDepartments(b.taxNo, pageSize)
+}
+
+func (b *Business) GetDepartments() ([]Department, error) {
+	return b.org.GetDepartments(b.taxNo)
+}
+
+func (b *Business) GetDepartmentAccounts(departmentUid uuid.UUID) ([]identity.Employee, error) {
+	return b.org.GetDepartmentAccounts(b.taxNo, departmentUid)
+}
+
+func (b *Business) GetSubordinateDepartmentAccounts(accountId string) ([]TeamMember, error) {
+	return b.org.GetSubordinateDepartmentAccounts(accountId, b.taxNo)
+}
+
+func (b *Business) GetHeadDepartmentAccounts(accountId string) ([]HeadDepartment, error) {
+	return b.org.GetHeadDepartmentAccounts(accountId, b.taxNo)
+}
+
+func (b *Business) GetRoles() ([]Role, error) {
+	return b.org.GetRoles(b.taxNo)
+}
+
+func (b *Business) GetRoleAccounts(roleUid uuid.UUID) ([]identity.Employee, error) {
+	return b.org.GetRoleAccounts(b.taxNo, roleUid)
+}
+
+func (b *Business) GetAccountRoles(accountId string) ([]AccountRole, error) {
+	return b.org.GetAccountRoles(accountId, b.taxNo)
+}
+
+func (b *Business) GetOrgChart() (OrgChart, error) {
+	return b.org.GetOrgChart(b.taxNo)
+}
+
+func (b *Business) TakeSnapshot() (Snapshot, error) {
+	return b.org.TakeSnapshot(b.taxNo)
+}
+
+func (b *Business) CreateDepartment(name string, parentUid *uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.CreateDepartment(b.taxNo, name, parentUid, opts...)
+}
+
+func (b *Business) RenameDepartment(departmentUid uuid.UUID, name string, opts ...WriteOption) (Change, error) {
+	return b.org.RenameDepartment(b.taxNo, departmentUid, name, opts...)
+}
+
+func (b *Business) MoveDepartment(departmentUid uuid.UUID, parentUid *uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.MoveDepartment(b.taxNo, departmentUid, parentUid, opts...)
+}
+
+func (b *Business) DeleteDepartment(departmentUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.DeleteDepartment(b.taxNo, departmentUid, opts...)
+}
+
+func (b *Business) AddDepartmentAccount(departmentUid uuid.UUID, accountId string, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.AddDepartmentAccount(b.taxNo, departmentUid, accountId, roleUid, opts...)
+}
+
+func (b *Business) RemoveDepartmentAccount(departmentUid uuid.UUID, accountId string, opts ...WriteOption) (Change, error) {
+	return b.org.RemoveDepartmentAccount(b.taxNo, departmentUid, accountId, opts...)
+}
+
+func (b *Business) MoveDepartmentAccount(accountId string, fromDepartmentUid uuid.UUID, toDepartmentUid uuid.UUID, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.MoveDepartmentAccount(b.taxNo, accountId, fromDepartmentUid, toDepartmentUid, roleUid, opts...)
+}
+
+func (b *Business) SetDepartmentAccountRole(departmentUid uuid.UUID, accountId string, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	return b.org.SetDepartmentAccountRole(b.taxNo, departmentUid, accountId, roleUid, opts...)
+}
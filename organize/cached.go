@@ -0,0 +1,115 @@
+package organize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/cache"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+const DefaultCacheTTL = 10 * time.Minute
+
+// CachedClient wraps department and profile lookups with a read-through cache. Concurrent misses
+// for the same key make a single upstream call. Results with partial errors are never cached.
+type CachedClient struct {
+	org     *OrgClient
+	id      *identity.Identity
+	cache   *cache.Cache
+	options CacheOptions
+}
+
+// NewCachedClient wraps org, and id for profiles which may be nil when profiles are not needed.
+func NewCachedClient(org *OrgClient, id *identity.Identity, options CacheOptions) *CachedClient {
+	if options.DepartmentsTTL == 0 {
+		options.DepartmentsTTL = DefaultCacheTTL
+	}
+	if options.DepartmentAccountsTTL == 0 {
+		options.DepartmentAccountsTTL = DefaultCacheTTL
+	}
+	if options.AccountRolesTTL == 0 {
+		options.AccountRolesTTL = DefaultCacheTTL
+	}
+	if options.ProfileTTL == 0 {
+		options.ProfileTTL = DefaultCacheTTL
+	}
+	return &CachedClient{
+		org:     org,
+		id:      id,
+		cache:   cache.New(options.Backend),
+		options: options,
+	}
+}
+
+func (c *CachedClient) GetDepartments(taxNo string) ([]Department, error) {
+	var dept []Department
+	err := c.cache.Fetch(departmentsKey(taxNo), c.options.DepartmentsTTL, &dept, func() (interface{}, error) {
+		return c.org.GetDepartments(taxNo)
+	})
+	return dept, err
+}
+
+func (c *CachedClient) GetDepartmentAccounts(taxNo string, departmentUid uuid.UUID) ([]identity.Employee, error) {
+	var employee []identity.Employee
+	err := c.cache.Fetch(departmentAccountsKey(taxNo, departmentUid), c.options.DepartmentAccountsTTL, &employee, func() (interface{}, error) {
+		return c.org.GetDepartmentAccounts(taxNo, departmentUid)
+	})
+	return employee, err
+}
+
+func (c *CachedClient) GetAccountRoles(accountId string, taxNo string) ([]AccountRole, error) {
+	var roles []AccountRole
+	err := c.cache.Fetch(accountRolesKey(taxNo, accountId), c.options.AccountRolesTTL, &roles, func() (interface{}, error) {
+		return c.org.GetAccountRoles(accountId, taxNo)
+	})
+	return roles, err
+}
+
+func (c *CachedClient) GetProfile(tokenType string, accessToken string) (identity.AccountProfile, error) {
+	var profile identity.AccountProfile
+	if c.id == nil {
+		return profile, errors.New("identity client required")
+	}
+	err := c.cache.Fetch(profileKey(accessToken), c.options.ProfileTTL, &profile, func() (interface{}, error) {
+		return c.id.GetProfile(tokenType, accessToken)
+	})
+	return profile, err
+}
+
+// InvalidateBusiness drops every cached department, membership and account role of a business.
+func (c *CachedClient) InvalidateBusiness(taxNo string) error {
+	return c.cache.InvalidatePrefix(fmt.Sprintf("org:%s:", taxNo))
+}
+
+func (c *CachedClient) InvalidateDepartment(taxNo string, departmentUid uuid.UUID) error {
+	return c.cache.Invalidate(departmentAccountsKey(taxNo, departmentUid))
+}
+
+func (c *CachedClient) InvalidateAccountRoles(taxNo string, accountId string) error {
+	return c.cache.Invalidate(accountRolesKey(taxNo, accountId))
+}
+
+func (c *CachedClient) InvalidateProfile(accessToken string) error {
+	return c.cache.Invalidate(profileKey(accessToken))
+}
+
+func departmentsKey(taxNo string) string {
+	return fmt.Sprintf("org:%s:departments", taxNo)
+}
+
+func departmentAccountsKey(taxNo string, departmentUid uuid.UUID) string {
+	return fmt.Sprintf("org:%s:department:%s", taxNo, departmentUid)
+}
+
+func accountRolesKey(taxNo string, accountId string) string {
+	return fmt.Sprintf("org:%s:account:%s:roles", taxNo, accountId)
+}
+
+// profileKey hashes the token so it is not kept in plain text by the backend.
+func profileKey(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return "profile:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,253 @@
+package organize
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GetOrgChart builds the department tree of a business together with members and heads.
+func (org *OrgClient) GetOrgChart(taxNo string) (OrgChart, error) {
+	var errs MultiError
+	departments, err := org.GetDepartments(taxNo)
+	if err := errs.merge(err); err != nil {
+		return OrgChart{}, err
+	}
+	members, heads, err := org.getDepartmentMembers(taxNo, departments)
+	if err := errs.merge(err); err != nil {
+		return OrgChart{}, err
+	}
+	return NewOrgChart(taxNo, departments, members, heads), errs.errOrNil()
+}
+
+// getDepartmentMembers loads members of every department. Heads are taken from the head-department
+// data of the first member, so departments without members have no known head. Malformed items
+// are collected into a MultiError and the remaining departments are still loaded.
+func (org *OrgClient) getDepartmentMembers(taxNo string, departments []Department) (map[uuid.UUID][]identity.Employee, map[uuid.UUID][]identity.Employee, error) {
+	members := map[uuid.UUID][]identity.Employee{}
+	heads := map[uuid.UUID][]identity.Employee{}
+	var errs MultiError
+	for _, d := range departments {
+		employees, err := org.GetDepartmentAccounts(taxNo, d.Id)
+		if err := errs.merge(err); err != nil {
+			return members, heads, err
+		}
+		members[d.Id] = employees
+		if _, ok := heads[d.Id]; ok || len(employees) == 0 {
+			continue
+		}
+		headDepartments, err := org.GetHeadDepartmentAccounts(employees[0].AccountId, taxNo)
+		if err := errs.merge(err); err != nil {
+			return members, heads, err
+		}
+		for _, hd := range headDepartments {
+			if hd.Accounts != nil {
+				heads[hd.Id] = *hd.Accounts
+			}
+		}
+	}
+	return members, heads, errs.errOrNil()
+}
+
+// NewOrgChart arranges departments into a tree. Departments without a known parent become roots.
+func NewOrgChart(taxNo string, departments []Department, members map[uuid.UUID][]identity.Employee, heads map[uuid.UUID][]identity.Employee) OrgChart {
+	nodes := map[uuid.UUID]*ChartNode{}
+	for _, d := range departments {
+		nodes[d.Id] = &ChartNode{
+			Department: d,
+			Heads:      heads[d.Id],
+			Members:    members[d.Id],
+		}
+	}
+	chart := OrgChart{TaxNo: taxNo}
+	for _, d := range departments {
+		node := nodes[d.Id]
+		if parent, ok := nodes[parentOf(d)]; ok && !isAncestor(nodes, d.Id, parent.Department) {
+			parent.Children = append(parent.Children, node)
+		} else {
+			chart.Roots = append(chart.Roots, node)
+		}
+	}
+	sortChartNodes(chart.Roots)
+	return chart
+}
+
+// LoadOrgChart reads a chart previously written with WriteJSON.
+func LoadOrgChart(r io.Reader) (OrgChart, error) {
+	var chart OrgChart
+	if err := json.NewDecoder(r).Decode(&chart); err != nil {
+		return chart, err
+	}
+	return chart, nil
+}
+
+// Walk visits every department in the chart depth first along with its path from the root.
+func (c OrgChart) Walk(fn func(path []string, node *ChartNode)) {
+	var walk func(path []string, nodes []*ChartNode)
+	walk = func(path []string, nodes []*ChartNode) {
+		for _, n := range nodes {
+			p := append(append([]string{}, path...), n.Department.Name)
+			fn(p, n)
+			walk(p, n.Children)
+		}
+	}
+	walk(nil, c.Roots)
+}
+
+func (c OrgChart) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// WriteDOT writes the chart as a Graphviz digraph. Department heads are filled in gold.
+func (c OrgChart) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph orgchart {\n")
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, fontname=\"Tahoma\"];\n")
+	c.Walk(func(path []string, n *ChartNode) {
+		deptId := n.Department.Id.String()
+		fmt.Fprintf(&b, "  %s [label=%s, style=bold];\n", dotQuote(deptId), dotQuote(n.Department.Name))
+		for _, child := range n.Children {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(deptId), dotQuote(child.Department.Id.String()))
+		}
+		for _, e := range n.Heads {
+			nodeId := fmt.Sprintf("%s/%s", deptId, e.AccountId)
+			fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse, style=filled, fillcolor=gold];\n", dotQuote(nodeId), dotQuote(employeeLabel(e)))
+			fmt.Fprintf(&b, "  %s -> %s [arrowhead=none, penwidth=2];\n", dotQuote(deptId), dotQuote(nodeId))
+		}
+		for _, e := range n.Members {
+			if n.isHead(e) {
+				continue
+			}
+			nodeId := fmt.Sprintf("%s/%s", deptId, e.AccountId)
+			fmt.Fprintf(&b, "  %s [label=%s, shape=ellipse];\n", dotQuote(nodeId), dotQuote(employeeLabel(e)))
+			fmt.Fprintf(&b, "  %s -> %s [arrowhead=none];\n", dotQuote(deptId), dotQuote(nodeId))
+		}
+	})
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes one row per department member with the full department path.
+func (c OrgChart) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"department", "employee", "position", "email", "head"}); err != nil {
+		return err
+	}
+	var err error
+	c.Walk(func(path []string, n *ChartNode) {
+		for _, e := range n.Members {
+			if err != nil {
+				return
+			}
+			head := ""
+			if n.isHead(e) {
+				head = "Y"
+			}
+			err = cw.Write([]string{strings.Join(path, " / "), employeeName(e), e.Role.Name, employeeEmail(e), head})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (n *ChartNode) isHead(e identity.Employee) bool {
+	for _, h := range n.Heads {
+		if h.AccountId == e.AccountId {
+			return true
+		}
+	}
+	return false
+}
+
+func parentOf(d Department) uuid.UUID {
+	if d.ParentDeptId == nil {
+		return uuid.Nil
+	}
+	return *d.ParentDeptId
+}
+
+// isAncestor reports whether id is found walking up from d, which would make attaching it a cycle.
+func isAncestor(nodes map[uuid.UUID]*ChartNode, id uuid.UUID, d Department) bool {
+	seen := map[uuid.UUID]bool{}
+	for {
+		if d.Id == id {
+			return true
+		}
+		if seen[d.Id] {
+			return true
+		}
+		seen[d.Id] = true
+		parent, ok := nodes[parentOf(d)]
+		if !ok {
+			return false
+		}
+		d = parent.Department
+	}
+}
+
+func sortChartNodes(nodes []*ChartNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Department.Name < nodes[j].Department.Name
+	})
+	for _, n := range nodes {
+		sortChartNodes(n.Children)
+	}
+}
+
+func employeeName(e identity.Employee) string {
+	if e.Account != nil {
+		if name := strings.TrimSpace(e.Account.FirstNameTH + " " + e.Account.LastNameTH); name != "" {
+			return name
+		}
+		if name := strings.TrimSpace(e.Account.FirstNameENG + " " + e.Account.LastNameENG); name != "" {
+			return name
+		}
+	}
+	if e.Email != "" {
+		return e.Email
+	}
+	return e.AccountId
+}
+
+func employeeEmail(e identity.Employee) string {
+	if e.Email != "" {
+		return e.Email
+	}
+	if e.Account != nil {
+		if e.Account.ThaiEmail1 != "" {
+			return e.Account.ThaiEmail1
+		}
+		for _, m := range e.Account.Email {
+			if m.Email != "" {
+				return m.Email
+			}
+		}
+	}
+	return ""
+}
+
+func employeeLabel(e identity.Employee) string {
+	if e.Role.Name == "" {
+		return employeeName(e)
+	}
+	return fmt.Sprintf("%s\n%s", employeeName(e), e.Role.Name)
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "\"", "\\\"", -1)
+	s = strings.Replace(s, "\n", "\\n", -1)
+	return "\"" + s + "\""
+}
@@ -0,0 +1,405 @@
+package organize
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"strings"
+)
+
+// utf8BOM lets Excel detect the encoding so Thai text is shown correctly.
+const utf8BOM = "\ufeff"
+
+// formulaChars start a cell that spreadsheets evaluate.
+const formulaChars = "=+-@\t\r"
+
+// EmployeeCSVColumns lists the columns ExportEmployeesCSV knows, in their default order.
+var EmployeeCSVColumns = []string{
+	"account_id",
+	"employee_id",
+	"title_th",
+	"first_name_th",
+	"last_name_th",
+	"title_eng",
+	"first_name_eng",
+	"last_name_eng",
+	"email",
+	"mobile",
+	"department_id",
+	"department",
+	"role_id",
+	"role",
+}
+
+type employeeRow struct {
+	account    identity.AccountProfile
+	membership Membership
+	department string
+}
+
+var employeeCSVValues = map[string]func(r employeeRow) string{
+	"account_id":     func(r employeeRow) string { return r.account.ID },
+	"employee_id":    func(r employeeRow) string { return r.membership.EmployeeId },
+	"title_th":       func(r employeeRow) string { return r.account.TitleTH },
+	"first_name_th":  func(r employeeRow) string { return r.account.FirstNameTH },
+	"last_name_th":   func(r employeeRow) string { return r.account.LastNameTH },
+	"title_eng":      func(r employeeRow) string { return r.account.TitleENG },
+	"first_name_eng": func(r employeeRow) string { return r.account.FirstNameENG },
+	"last_name_eng":  func(r employeeRow) string { return r.account.LastNameENG },
+	"email": func(r employeeRow) string {
+		if r.membership.Email != "" {
+			return r.membership.Email
+		}
+		return employeeEmail(identity.Employee{Account: &r.account})
+	},
+	"mobile": func(r employeeRow) string {
+		for _, m := range r.account.Mobile {
+			if m.MobileNumber != "" {
+				return m.MobileNumber
+			}
+		}
+		return r.account.TelephoneNumber
+	},
+	"department_id": func(r employeeRow) string {
+		if r.membership.DepartmentId == uuid.Nil {
+			return ""
+		}
+		return r.membership.DepartmentId.String()
+	},
+	"department": func(r employeeRow) string { return r.department },
+	"role_id": func(r employeeRow) string {
+		if r.membership.Role.Id == uuid.Nil {
+			return ""
+		}
+		return r.membership.Role.Id.String()
+	},
+	"role": func(r employeeRow) string { return r.membership.Role.Name },
+}
+
+// ExportEmployeesCSV writes one row per department membership of the snapshot, accounts without
+// a department get a single row. No columns means EmployeeCSVColumns. Cells that a spreadsheet
+// would run as a formula are prefixed with a quote, NewImportPlan removes it again.
+func ExportEmployeesCSV(w io.Writer, s Snapshot, columns ...string) error {
+	if len(columns) == 0 {
+		columns = EmployeeCSVColumns
+	}
+	for _, c := range columns {
+		if _, ok := employeeCSVValues[c]; !ok {
+			return fmt.Errorf("unknown column %q", c)
+		}
+	}
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	paths := s.departmentPaths()
+	members := s.membershipIndex()
+	for _, a := range s.Accounts {
+		rows := []employeeRow{{account: a}}
+		if ms := members[a.ID]; len(ms) > 0 {
+			rows = rows[:0]
+			for _, m := range ms {
+				rows = append(rows, employeeRow{account: a, membership: m, department: paths[m.DepartmentId]})
+			}
+		}
+		for _, r := range rows {
+			record := make([]string, len(columns))
+			for i, c := range columns {
+				record[i] = escapeFormula(employeeCSVValues[c](r))
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// PlanEmployeeImport reads a CSV of intended memberships and compares it with the live data of a business.
+func (org *OrgClient) PlanEmployeeImport(taxNo string, r io.Reader, options ImportOptions) (ImportPlan, error) {
+	var errs MultiError
+	snapshot, err := org.TakeSnapshot(taxNo)
+	if err := errs.merge(err); err != nil {
+		return ImportPlan{}, err
+	}
+	roles, err := org.GetRoles(taxNo)
+	if err := errs.merge(err); err != nil {
+		return ImportPlan{}, err
+	}
+	plan, err := NewImportPlan(r, snapshot, roles, options)
+	if err != nil {
+		return plan, err
+	}
+	return plan, errs.errOrNil()
+}
+
+// NewImportPlan reads a CSV of intended memberships and works out the changes needed to get there
+// from the snapshot. The CSV uses the export column names: the account is found by account_id or
+// email, the department by department_id or its path in department, the role by role_id or role.
+// A role is required to add a membership, a move keeps the current role when none is given.
+// An optional action column set to "remove" removes the membership instead. Invalid rows are
+// reported in the plan and left out of the changes, a failing reader ends the plan with its error.
+func NewImportPlan(r io.Reader, s Snapshot, roles []Role, options ImportOptions) (ImportPlan, error) {
+	var plan ImportPlan
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return plan, err
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, utf8BOM)))] = i
+	}
+	_, hasAccount := columns["account_id"]
+	_, hasEmail := columns["email"]
+	_, hasDeptId := columns["department_id"]
+	_, hasDept := columns["department"]
+	if !hasAccount && !hasEmail {
+		return plan, errors.New("csv requires an account_id or email column")
+	}
+	if !hasDeptId && !hasDept {
+		return plan, errors.New("csv requires a department_id or department column")
+	}
+
+	accounts := s.accountIndex()
+	emails := map[string]string{}
+	for _, a := range s.Accounts {
+		for _, e := range []string{a.ThaiEmail1, a.ThaiEmail2} {
+			if e != "" {
+				emails[strings.ToLower(e)] = a.ID
+			}
+		}
+		for _, e := range a.Email {
+			emails[strings.ToLower(e.Email)] = a.ID
+		}
+	}
+	for _, m := range s.Memberships {
+		if m.Email != "" {
+			emails[strings.ToLower(m.Email)] = m.AccountId
+		}
+	}
+	departments := map[uuid.UUID]Department{}
+	for _, d := range s.Departments {
+		departments[d.Id] = d
+	}
+	byPath := map[string]uuid.UUID{}
+	for id, p := range s.departmentPaths() {
+		byPath[strings.ToLower(p)] = id
+	}
+	roleById := map[uuid.UUID]Role{}
+	roleByName := map[string]Role{}
+	for _, r := range roles {
+		roleById[r.Id] = r
+		roleByName[strings.ToLower(r.Name)] = r
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(unescapeFormula(record[i]))
+		}
+		return ""
+	}
+	intended := map[string][]Membership{}
+	removals := map[string][]Membership{}
+	rowOf := map[string]int{}
+	rowOfMembership := map[string]int{}
+	var order []string
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			plan.Errors = append(plan.Errors, &RowError{Row: row, Err: err})
+			continue
+		}
+		if err != nil {
+			return plan, err
+		}
+		accountId := field(record, "account_id")
+		if accountId == "" {
+			accountId = emails[strings.ToLower(field(record, "email"))]
+		}
+		if _, ok := accounts[accountId]; !ok || accountId == "" {
+			plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "account_id", Err: ErrNotFound})
+			continue
+		}
+		var deptId uuid.UUID
+		if v := field(record, "department_id"); v != "" {
+			deptId = uuid.FromStringOrNil(v)
+		} else {
+			deptId = byPath[strings.ToLower(field(record, "department"))]
+		}
+		if _, ok := departments[deptId]; !ok {
+			plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "department", Err: ErrNotFound})
+			continue
+		}
+		var role Role
+		if v := field(record, "role_id"); v != "" {
+			r, ok := roleById[uuid.FromStringOrNil(v)]
+			if !ok {
+				plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "role_id", Err: ErrNotFound})
+				continue
+			}
+			role = r
+		} else if v := field(record, "role"); v != "" {
+			r, ok := roleByName[strings.ToLower(v)]
+			if !ok {
+				plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "role", Err: ErrNotFound})
+				continue
+			}
+			role = r
+		}
+		m := Membership{DepartmentId: deptId, AccountId: accountId, Role: role}
+		switch action := strings.ToLower(field(record, "action")); action {
+		case "", "add", "keep":
+			if _, dup := findMembership(intended[accountId], deptId); dup {
+				plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "department", Err: fmt.Errorf("%w: duplicate department for account", ErrInvalidChange)})
+				continue
+			}
+			intended[accountId] = append(intended[accountId], m)
+			rowOfMembership[accountId+"/"+deptId.String()] = row
+		case "remove":
+			removals[accountId] = append(removals[accountId], m)
+		default:
+			plan.Errors = append(plan.Errors, &RowError{Row: row, Column: "action", Err: fmt.Errorf("%w: unknown action %q", ErrInvalidChange, action)})
+			continue
+		}
+		if _, ok := rowOf[accountId]; !ok {
+			rowOf[accountId] = row
+			order = append(order, accountId)
+		}
+	}
+
+	current := s.membershipIndex()
+	for _, accountId := range order {
+		row := rowOf[accountId]
+		var leftover []Membership
+		for _, m := range current[accountId] {
+			if _, keep := findMembership(intended[accountId], m.DepartmentId); keep {
+				continue
+			}
+			if _, remove := findMembership(removals[accountId], m.DepartmentId); remove {
+				plan.Changes = append(plan.Changes, PlannedChange{Row: row, Action: RemoveAccountChange, AccountId: accountId, FromDepartmentId: m.DepartmentId})
+				continue
+			}
+			leftover = append(leftover, m)
+		}
+		for _, m := range intended[accountId] {
+			mrow := rowOfMembership[accountId+"/"+m.DepartmentId.String()]
+			if c, ok := findMembership(current[accountId], m.DepartmentId); ok {
+				if m.Role.Id != uuid.Nil && c.Role.Id != m.Role.Id {
+					plan.Changes = append(plan.Changes, PlannedChange{Row: mrow, Action: ChangeRoleChange, AccountId: accountId, DepartmentId: m.DepartmentId, Role: m.Role})
+				}
+				continue
+			}
+			if options.RemoveUnlisted && len(leftover) > 0 {
+				// a move without a role keeps the role held in the department left
+				role := m.Role
+				if role.Id == uuid.Nil {
+					role = leftover[0].Role
+				}
+				if role.Id == uuid.Nil {
+					plan.Errors = append(plan.Errors, &RowError{Row: mrow, Column: "role", Err: fmt.Errorf("%w: role required", ErrInvalidChange)})
+					continue
+				}
+				plan.Changes = append(plan.Changes, PlannedChange{Row: mrow, Action: MoveAccountChange, AccountId: accountId, FromDepartmentId: leftover[0].DepartmentId, DepartmentId: m.DepartmentId, Role: role})
+				leftover = leftover[1:]
+				continue
+			}
+			if m.Role.Id == uuid.Nil {
+				plan.Errors = append(plan.Errors, &RowError{Row: mrow, Column: "role", Err: fmt.Errorf("%w: role required", ErrInvalidChange)})
+				continue
+			}
+			plan.Changes = append(plan.Changes, PlannedChange{Row: mrow, Action: AddAccountChange, AccountId: accountId, DepartmentId: m.DepartmentId, Role: m.Role})
+		}
+		if options.RemoveUnlisted {
+			for _, m := range leftover {
+				plan.Changes = append(plan.Changes, PlannedChange{Row: row, Action: RemoveAccountChange, AccountId: accountId, FromDepartmentId: m.DepartmentId})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// ApplyImportPlan makes the planned changes through the write api. Failed changes do not stop the
+// others, they are returned together as StepErrors.
+func (org *OrgClient) ApplyImportPlan(taxNo string, plan ImportPlan, opts ...WriteOption) ([]Change, error) {
+	var changes []Change
+	var errs StepErrors
+	for _, p := range plan.Changes {
+		var c Change
+		var err error
+		switch p.Action {
+		case AddAccountChange:
+			c, err = org.AddDepartmentAccount(taxNo, p.DepartmentId, p.AccountId, p.Role.Id, opts...)
+		case MoveAccountChange:
+			c, err = org.MoveDepartmentAccount(taxNo, p.AccountId, p.FromDepartmentId, p.DepartmentId, p.Role.Id, opts...)
+		case ChangeRoleChange:
+			c, err = org.SetDepartmentAccountRole(taxNo, p.DepartmentId, p.AccountId, p.Role.Id, opts...)
+		case RemoveAccountChange:
+			c, err = org.RemoveDepartmentAccount(taxNo, p.FromDepartmentId, p.AccountId, opts...)
+		default:
+			err = fmt.Errorf("%w: unsupported action %q", ErrInvalidChange, p.Action)
+		}
+		if err != nil {
+			errs = append(errs, &RowError{Row: p.Row, Err: err})
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, errs.errOrNil()
+}
+
+func (b *Business) PlanEmployeeImport(r io.Reader, options ImportOptions) (ImportPlan, error) {
+	return b.org.PlanEmployeeImport(b.taxNo, r, options)
+}
+
+func (b *Business) ApplyImportPlan(plan ImportPlan, opts ...WriteOption) ([]Change, error) {
+	return b.org.ApplyImportPlan(b.taxNo, plan, opts...)
+}
+
+func (e *RowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("row %d %s: %s", e.Row, e.Column, e.Err)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// departmentPaths returns the full "parent / child" path of every department.
+func (s Snapshot) departmentPaths() map[uuid.UUID]string {
+	paths := map[uuid.UUID]string{}
+	s.Chart().Walk(func(path []string, n *ChartNode) {
+		paths[n.Department.Id] = strings.Join(path, " / ")
+	})
+	return paths
+}
+
+// escapeFormula quotes a cell starting with a character spreadsheets read as a formula, such as a
+// name starting with "=" or a mobile number starting with "+".
+func escapeFormula(v string) string {
+	if v != "" && strings.ContainsRune(formulaChars, rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func unescapeFormula(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune(formulaChars, rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
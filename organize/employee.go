@@ -0,0 +1,195 @@
+package organize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// InviteEmployee invites a person by email or mobile number into a department with a role.
+// The person becomes a member once the invitation is accepted.
+func (org *OrgClient) InviteEmployee(taxNo string, contact string, departmentUid uuid.UUID, roleUid uuid.UUID, opts ...WriteOption) (Invitation, error) {
+	invitation := Invitation{
+		DepartmentId: departmentUid,
+		RoleId:       roleUid,
+		Status:       InvitationPending,
+	}
+	if roleUid == uuid.Nil {
+		return invitation, fmt.Errorf("%w: role required", ErrInvalidChange)
+	}
+	params := map[string]interface{}{
+		"dept_id": departmentUid.String(),
+		"role_id": roleUid.String(),
+	}
+	if strings.Contains(contact, "@") {
+		addr, err := mail.ParseAddress(contact)
+		if err != nil {
+			return invitation, fmt.Errorf("%w: invalid email %q", ErrInvalidChange, contact)
+		}
+		invitation.Email = addr.Address
+		params["email"] = addr.Address
+	} else {
+		mobile := normalizeMobile(contact)
+		if len(mobile) != 10 || mobile[0] != '0' {
+			return invitation, fmt.Errorf("%w: invalid mobile number %q", ErrInvalidChange, contact)
+		}
+		invitation.Mobile = mobile
+		params["mobile_no"] = mobile
+	}
+	c := Change{
+		Action:       InviteEmployeeChange,
+		Method:       http.MethodPost,
+		Path:         "/invitation",
+		DepartmentId: departmentUid,
+		Payload:      params,
+	}
+	var created Invitation
+	c, err := org.apply(taxNo, c, &created, opts)
+	if err != nil || !c.Applied {
+		return invitation, err
+	}
+	if created.Id == "" {
+		return invitation, &DecodeError{Path: c.Path, Index: -1, Err: errors.New("missing invitation id")}
+	}
+	return created, nil
+}
+
+func (org *OrgClient) GetInvitation(taxNo string, invitationId string) (Invitation, error) {
+	var invitation Invitation
+	path := fmt.Sprintf("/invitation/%s", invitationId)
+	data, err := org.get(path, taxNo)
+	if err != nil {
+		return invitation, err
+	}
+	err = decodeData(path, data, &invitation)
+	return invitation, err
+}
+
+// GetInvitations lists invitations of a business, an empty status lists all of them.
+func (org *OrgClient) GetInvitations(taxNo string, status InvitationStatus) ([]Invitation, error) {
+	var invitations []Invitation
+	data, err := org.get("/invitation", taxNo)
+	if err != nil {
+		return invitations, err
+	}
+	err = decodeList("/invitation", data, func(i int, raw json.RawMessage) error {
+		var inv Invitation
+		if err := json.Unmarshal(raw, &inv); err != nil {
+			return err
+		}
+		if status == "" || inv.Status == status {
+			invitations = append(invitations, inv)
+		}
+		return nil
+	})
+	return invitations, err
+}
+
+func (org *OrgClient) CancelInvitation(taxNo string, invitationId string, opts ...WriteOption) (Change, error) {
+	c := Change{
+		Action: CancelInvitationChange,
+		Method: http.MethodDelete,
+		Path:   fmt.Sprintf("/invitation/%s", invitationId),
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+// RemoveEmployee offboards an account: it leaves every department, is removed from the business
+// and optionally has its sessions revoked. The account is only removed once all memberships are
+// cleared, sessions are revoked regardless. Every step is reported, failed steps are also
+// returned together as StepErrors.
+func (org *OrgClient) RemoveEmployee(taxNo string, accountId string, options RemoveOptions, opts ...WriteOption) (WorkflowReport, error) {
+	report := WorkflowReport{AccountId: accountId}
+	if accountId == "" {
+		return report, fmt.Errorf("%w: account id required", ErrInvalidChange)
+	}
+	if err := ValidateTaxNo(taxNo); err != nil {
+		return report, err
+	}
+
+	roles, err := org.GetAccountRoles(accountId, taxNo)
+	report.add(WorkflowStep{Name: "load_departments", Err: err})
+	cleared := err == nil
+	for _, r := range roles {
+		c, err := org.RemoveDepartmentAccount(taxNo, r.DepartmentId, accountId, opts...)
+		report.add(WorkflowStep{Name: "leave_department", Change: &c, Err: err})
+		cleared = cleared && err == nil
+	}
+
+	if cleared {
+		c, err := org.apply(taxNo, Change{
+			Action:    RemoveEmployeeChange,
+			Method:    http.MethodDelete,
+			Path:      fmt.Sprintf("/account/%s", accountId),
+			AccountId: accountId,
+		}, nil, opts)
+		report.add(WorkflowStep{Name: "remove_account", Change: &c, Err: err})
+	} else {
+		report.add(WorkflowStep{Name: "remove_account", Skipped: true})
+	}
+
+	if options.RevokeSessions {
+		c, err := org.apply(taxNo, Change{
+			Action:    RevokeSessionsChange,
+			Method:    http.MethodPost,
+			Path:      fmt.Sprintf("/account/%s/revoke-session", accountId),
+			AccountId: accountId,
+		}, nil, opts)
+		report.add(WorkflowStep{Name: "revoke_sessions", Change: &c, Err: err})
+	}
+	return report, report.Err()
+}
+
+func (b *Business) InviteEmployee(contact string, departmentUid uuid.UUID, roleUid uuid.UUID, opts ...WriteOption) (Invitation, error) {
+	return b.org.InviteEmployee(b.taxNo, contact, departmentUid, roleUid, opts...)
+}
+
+func (b *Business) GetInvitation(invitationId string) (Invitation, error) {
+	return b.org.GetInvitation(b.taxNo, invitationId)
+}
+
+func (b *Business) GetInvitations(status InvitationStatus) ([]Invitation, error) {
+	return b.org.GetInvitations(b.taxNo, status)
+}
+
+func (b *Business) CancelInvitation(invitationId string, opts ...WriteOption) (Change, error) {
+	return b.org.CancelInvitation(b.taxNo, invitationId, opts...)
+}
+
+func (b *Business) RemoveEmployee(accountId string, options RemoveOptions, opts ...WriteOption) (WorkflowReport, error) {
+	return b.org.RemoveEmployee(b.taxNo, accountId, options, opts...)
+}
+
+// Err returns the failed steps as StepErrors, or nil when every step succeeded.
+func (r WorkflowReport) Err() error {
+	var errs StepErrors
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, s.Err))
+		}
+	}
+	return errs.errOrNil()
+}
+
+func (r *WorkflowReport) add(step WorkflowStep) {
+	r.Steps = append(r.Steps, step)
+}
+
+// normalizeMobile keeps the digits of a phone number and turns the +66 country code into a leading zero.
+func normalizeMobile(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	digits := b.String()
+	if strings.HasPrefix(digits, "66") && len(digits) == 11 {
+		digits = "0" + digits[2:]
+	}
+	return digits
+}
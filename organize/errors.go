@@ -0,0 +1,134 @@
+package organize
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrConflict         = errors.New("conflict")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrInvalidChange    = errors.New("invalid change")
+)
+
+// ApiError is returned when the server answers with a non 200 status or an error envelope.
+type ApiError struct {
+	StatusCode int
+	Code       int
+	Result     string
+	Message    string
+}
+
+func (e *ApiError) Error() string {
+	if e.Result != "" {
+		return fmt.Sprintf("server return code %d %s: %s", e.StatusCode, e.Result, e.Message)
+	}
+	return fmt.Sprintf("server return code %d %s", e.StatusCode, e.Message)
+}
+
+// Is lets errors.Is match an ApiError against ErrNotFound, ErrConflict and ErrPermissionDenied.
+func (e *ApiError) Is(target error) bool {
+	code := e.Code
+	if code == 0 || code == http.StatusOK {
+		code = e.StatusCode
+	}
+	switch target {
+	case ErrNotFound:
+		return code == http.StatusNotFound
+	case ErrConflict:
+		return code == http.StatusConflict
+	case ErrPermissionDenied:
+		return code == http.StatusUnauthorized || code == http.StatusForbidden
+	}
+	return false
+}
+
+// DecodeError describes a response item that could not be decoded. Index is -1 when the
+// whole response data is malformed.
+type DecodeError struct {
+	Path  string
+	Index int
+	Raw   json.RawMessage
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("decode %s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("decode %s[%d]: %s", e.Path, e.Index, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned next to partial results when some items of a response were malformed.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msg := make([]string, len(m))
+	for i, err := range m {
+		msg[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m), strings.Join(msg, "; "))
+}
+
+func (m MultiError) errOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// merge keeps partial errors and gives back any other error so the caller can stop.
+func (m *MultiError) merge(err error) error {
+	if err == nil {
+		return nil
+	}
+	if partial, ok := err.(MultiError); ok {
+		*m = append(*m, partial...)
+		return nil
+	}
+	return err
+}
+
+// StepErrors is returned when some steps of a workflow or an import failed while the others were
+// still made. Unlike a MultiError these are failed writes, not malformed response items.
+type StepErrors []error
+
+func (s StepErrors) Error() string {
+	msg := make([]string, len(s))
+	for i, err := range s {
+		msg[i] = err.Error()
+	}
+	return fmt.Sprintf("%d steps failed: %s", len(s), strings.Join(msg, "; "))
+}
+
+// Is lets errors.Is match a target against any of the failed steps.
+func (s StepErrors) Is(target error) bool {
+	for _, err := range s {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s StepErrors) errOrNil() error {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+// IsPartial reports whether err only carries malformed items, meaning the results returned
+// alongside it are still usable.
+func IsPartial(err error) bool {
+	_, ok := err.(MultiError)
+	return ok
+}
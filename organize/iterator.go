@@ -0,0 +1,184 @@
+package organize
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+)
+
+const DefaultPageSize = 100
+
+// AccountIterator streams the accounts of a business one page at a time.
+//
+//	it := org.IterateAccounts(taxNo, 500)
+//	for it.Next() {
+//		a := it.Account()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type AccountIterator struct {
+	pager
+	items   []identity.AccountProfile
+	current identity.AccountProfile
+}
+
+// DepartmentIterator streams the departments of a business one page at a time.
+type DepartmentIterator struct {
+	pager
+	items   []Department
+	current Department
+}
+
+// IterateAccounts returns an iterator over the accounts of a business, a page size of zero or
+// less uses DefaultPageSize.
+func (org *OrgClient) IterateAccounts(taxNo string, pageSize int) *AccountIterator {
+	return &AccountIterator{pager: newPager(org, "/account", taxNo, pageSize)}
+}
+
+func (org *OrgClient) IterateDepartments(taxNo string, pageSize int) *DepartmentIterator {
+	return &DepartmentIterator{pager: newPager(org, "/department", taxNo, pageSize)}
+}
+
+func (it *AccountIterator) Next() bool {
+	for len(it.items) == 0 {
+		if !it.fetch(func(raw json.RawMessage) error {
+			var a identity.AccountProfile
+			if err := json.Unmarshal(raw, &a); err != nil {
+				return err
+			}
+			it.items = append(it.items, a)
+			return nil
+		}) {
+			return false
+		}
+	}
+	it.current, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+func (it *AccountIterator) Account() identity.AccountProfile {
+	return it.current
+}
+
+func (it *DepartmentIterator) Next() bool {
+	for len(it.items) == 0 {
+		if !it.fetch(func(raw json.RawMessage) error {
+			var r departmentRecord
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			d, err := r.department()
+			if err != nil {
+				return err
+			}
+			it.items = append(it.items, d)
+			return nil
+		}) {
+			return false
+		}
+	}
+	it.current, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+func (it *DepartmentIterator) Department() Department {
+	return it.current
+}
+
+type pager struct {
+	org      *OrgClient
+	path     string
+	taxNo    string
+	pageSize int
+	page     int
+	first    [sha256.Size]byte
+	done     bool
+	err      error
+	errs     MultiError
+}
+
+func newPager(org *OrgClient, path string, taxNo string, pageSize int) pager {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return pager{org: org, path: path, taxNo: taxNo, pageSize: pageSize}
+}
+
+// Err returns the error that stopped the iteration. Malformed items do not stop it, they are
+// returned here as a MultiError once the iteration is over.
+func (p *pager) Err() error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.errs.errOrNil()
+}
+
+// fetch loads the next page and streams its items into decode. Iteration ends on a short page, or
+// when a page starts with the same raw item as the previous one since the server then ignores
+// paging. The raw item is compared so a malformed first item does not defeat the check.
+func (p *pager) fetch(decode func(raw json.RawMessage) error) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	p.page++
+	uri := fmt.Sprintf("%s?page=%d&per_page=%d", p.path, p.page, p.pageSize)
+	data, err := p.org.get(uri, p.taxNo)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if len(data) == 0 || string(data) == "null" {
+		p.done = true
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		p.err = &DecodeError{Path: uri, Index: -1, Raw: data, Err: errors.New("data is not a list")}
+		return false
+	}
+	count := 0
+	for ; dec.More(); count++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			p.err = &DecodeError{Path: uri, Index: count, Err: err}
+			return false
+		}
+		if count == 0 {
+			first := sha256.Sum256(raw)
+			if p.page > 1 && first == p.first {
+				p.done = true
+				return false
+			}
+			p.first = first
+		}
+		if err := decode(raw); err != nil {
+			p.errs = append(p.errs, &DecodeError{Path: uri, Index: count, Raw: raw, Err: err})
+		}
+	}
+	if count < p.pageSize || count > p.pageSize {
+		p.done = true
+	}
+	return count > 0
+}
+
+func (r departmentRecord) department() (Department, error) {
+	if r.Id == nil || *r.Id == uuid.Nil {
+		return Department{}, errors.New("missing department id")
+	}
+	if r.Name == nil {
+		return Department{}, errors.New("missing department name")
+	}
+	parentDeptId := uuid.Nil
+	if r.ParentDeptId != nil {
+		parentDeptId = *r.ParentDeptId
+	}
+	return Department{
+		Id:           *r.Id,
+		Name:         *r.Name,
+		ParentDeptId: &parentDeptId,
+	}, nil
+}
@@ -1,8 +1,12 @@
 package organize
 
 import (
+	"encoding/json"
+	"github.com/inetspa/oneplatform-sdk-go/cache"
 	"github.com/inetspa/oneplatform-sdk-go/identity"
+	"github.com/inetspa/oneplatform-sdk-go/ratelimit"
 	uuid "github.com/satori/go.uuid"
+	"time"
 )
 
 type OrgClient struct {
@@ -10,13 +14,30 @@ type OrgClient struct {
 	RefreshToken string `json:"refresh_token"`
 	TokenType    string `json:"type"`
 	ApiEndpoint  string `json:"api_endpoint"`
+	timeout      int
+	limiter      ratelimit.Limiter
 }
 
 type OrgApiResult struct {
-	Result string      `json:"result"`
-	Data   interface{} `json:"data"`
-	Error  interface{} `json:"errorMessage"`
-	Code   int         `json:"code"`
+	Result string          `json:"result"`
+	Data   json.RawMessage `json:"data"`
+	Error  interface{}     `json:"errorMessage"`
+	Code   int             `json:"code"`
+}
+
+// Business the authenticated account belongs to
+type BusinessInfo struct {
+	Id      string `json:"id"`
+	TaxNo   string `json:"tax_id"`
+	NameTH  string `json:"name_th"`
+	NameENG string `json:"name_eng"`
+	Role    Role   `json:"role"`
+}
+
+// Business scoped client, see OrgClient.Business
+type Business struct {
+	org   *OrgClient
+	taxNo string
 }
 
 type Department struct {
@@ -39,3 +60,239 @@ type HeadDepartment struct {
 	ParentDeptId *uuid.UUID           `json:"parent_dept_id"`
 	Accounts     *[]identity.Employee `json:"has_account"`
 }
+
+type departmentRecord struct {
+	Id           *uuid.UUID `json:"id"`
+	Name         *string    `json:"dept_name"`
+	ParentDeptId *uuid.UUID `json:"parent_dept_id"`
+}
+
+type departmentAccountsRecord struct {
+	HasRole    []json.RawMessage `json:"has_role"`
+	HasAccount []json.RawMessage `json:"has_account"`
+}
+
+type Role = identity.Role
+
+// Role held by an account in one department
+type AccountRole struct {
+	DepartmentId uuid.UUID   `json:"dept_id"`
+	Department   *Department `json:"department"`
+	Role         Role        `json:"role"`
+}
+
+type roleRecord struct {
+	RoleId uuid.UUID `json:"role_id"`
+	Role   *Role     `json:"role"`
+}
+
+type OrgChart struct {
+	TaxNo string       `json:"tax_id"`
+	Roots []*ChartNode `json:"departments"`
+}
+
+type ChartNode struct {
+	Department Department          `json:"department"`
+	Heads      []identity.Employee `json:"heads,omitempty"`
+	Members    []identity.Employee `json:"members,omitempty"`
+	Children   []*ChartNode        `json:"children,omitempty"`
+}
+
+// Snapshot of a business directory, serializable with Save and LoadSnapshot
+type Snapshot struct {
+	TaxNo       string                    `json:"tax_id"`
+	TakenAt     time.Time                 `json:"taken_at"`
+	Accounts    []identity.AccountProfile `json:"accounts"`
+	Departments []Department              `json:"departments"`
+	Memberships []Membership              `json:"memberships"`
+	Heads       []DepartmentHead          `json:"heads"`
+}
+
+type Membership struct {
+	DepartmentId uuid.UUID `json:"department_id"`
+	AccountId    string    `json:"account_id"`
+	EmployeeId   string    `json:"employee_id"`
+	Email        string    `json:"email"`
+	Role         Role      `json:"role"`
+}
+
+type DepartmentHead struct {
+	DepartmentId uuid.UUID `json:"department_id"`
+	AccountId    string    `json:"account_id"`
+}
+
+type EventType string
+
+const (
+	AccountJoined        EventType = "account_joined"
+	AccountLeft          EventType = "account_left"
+	AccountMoved         EventType = "account_moved"
+	PositionChanged      EventType = "position_changed"
+	DepartmentCreated    EventType = "department_created"
+	DepartmentRenamed    EventType = "department_renamed"
+	DepartmentReparented EventType = "department_reparented"
+	DepartmentDeleted    EventType = "department_deleted"
+)
+
+// Snapshot diff event. From and To hold names before and after the change,
+// an empty From or To on AccountMoved means the membership was only added or removed.
+type Event struct {
+	Type         EventType `json:"type"`
+	AccountId    string    `json:"account_id,omitempty"`
+	DepartmentId uuid.UUID `json:"department_id"`
+	From         string    `json:"from,omitempty"`
+	To           string    `json:"to,omitempty"`
+}
+
+type ApprovalOptions struct {
+	// MaxDepth limits the number of approvers, zero means up to the root department.
+	MaxDepth int
+	// StopAt ends the chain once the head of this department has been added.
+	StopAt *uuid.UUID
+}
+
+type Approver struct {
+	Level      int               `json:"level"`
+	Department Department        `json:"department"`
+	Employee   identity.Employee `json:"employee"`
+}
+
+type ChangeAction string
+
+const (
+	CreateDepartmentChange ChangeAction = "create_department"
+	RenameDepartmentChange ChangeAction = "rename_department"
+	MoveDepartmentChange   ChangeAction = "move_department"
+	DeleteDepartmentChange ChangeAction = "delete_department"
+	AddAccountChange       ChangeAction = "add_account"
+	RemoveAccountChange    ChangeAction = "remove_account"
+	MoveAccountChange      ChangeAction = "move_account"
+	ChangeRoleChange       ChangeAction = "change_role"
+	InviteEmployeeChange   ChangeAction = "invite_employee"
+	CancelInvitationChange ChangeAction = "cancel_invitation"
+	RemoveEmployeeChange   ChangeAction = "remove_employee"
+	RevokeSessionsChange   ChangeAction = "revoke_sessions"
+)
+
+// Change is the request made, or to be made on a dry run, by a write operation
+type Change struct {
+	Action       ChangeAction           `json:"action"`
+	Method       string                 `json:"method"`
+	Path         string                 `json:"path"`
+	Payload      map[string]interface{} `json:"payload,omitempty"`
+	DepartmentId uuid.UUID              `json:"department_id"`
+	AccountId    string                 `json:"account_id,omitempty"`
+	Applied      bool                   `json:"applied"`
+}
+
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	dryRun bool
+}
+
+type InvitationStatus string
+
+const (
+	InvitationPending   InvitationStatus = "pending"
+	InvitationAccepted  InvitationStatus = "accepted"
+	InvitationRejected  InvitationStatus = "rejected"
+	InvitationExpired   InvitationStatus = "expired"
+	InvitationCancelled InvitationStatus = "cancelled"
+)
+
+type Invitation struct {
+	Id           string           `json:"id"`
+	Email        string           `json:"email,omitempty"`
+	Mobile       string           `json:"mobile_no,omitempty"`
+	DepartmentId uuid.UUID        `json:"dept_id"`
+	RoleId       uuid.UUID        `json:"role_id"`
+	Status       InvitationStatus `json:"status"`
+	AccountId    string           `json:"account_id,omitempty"`
+	CreatedAt    string           `json:"created_at"`
+	UpdatedAt    string           `json:"updated_at"`
+}
+
+type RemoveOptions struct {
+	RevokeSessions bool
+}
+
+// Result of every step of an employee workflow, in the order they ran
+type WorkflowReport struct {
+	AccountId string         `json:"account_id"`
+	Steps     []WorkflowStep `json:"steps"`
+}
+
+type WorkflowStep struct {
+	Name    string  `json:"name"`
+	Change  *Change `json:"change,omitempty"`
+	Skipped bool    `json:"skipped,omitempty"`
+	Err     error   `json:"-"`
+}
+
+// Cache settings of CachedClient, a zero TTL uses DefaultCacheTTL
+type CacheOptions struct {
+	Backend               cache.Backend
+	DepartmentsTTL        time.Duration
+	DepartmentAccountsTTL time.Duration
+	AccountRolesTTL       time.Duration
+	ProfileTTL            time.Duration
+}
+
+type SearchResult struct {
+	Account identity.AccountProfile `json:"account"`
+	Field   string                  `json:"field"`
+	Score   float64                 `json:"score"`
+}
+
+type ImportOptions struct {
+	// RemoveUnlisted removes memberships of listed accounts that are not in the CSV,
+	// pairing them with new departments as moves where possible.
+	RemoveUnlisted bool
+}
+
+type ImportPlan struct {
+	Changes []PlannedChange `json:"changes"`
+	Errors  []error         `json:"-"`
+}
+
+type PlannedChange struct {
+	Row              int          `json:"row"`
+	Action           ChangeAction `json:"action"`
+	AccountId        string       `json:"account_id"`
+	FromDepartmentId uuid.UUID    `json:"from_department_id"`
+	DepartmentId     uuid.UUID    `json:"department_id"`
+	Role             Role         `json:"role"`
+}
+
+// RowError reports an invalid CSV row, Row counts the header as row 1
+type RowError struct {
+	Row    int
+	Column string
+	Err    error
+}
+
+type BulkOptions struct {
+	// Workers is the number of concurrent requests, zero means DefaultBulkWorkers.
+	Workers int
+}
+
+// BulkError lists the departments a bulk call could not load, by department id
+type BulkError struct {
+	Total    int
+	Failures map[uuid.UUID]error
+}
+
+// Policy allows an account matching any of its rules.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule matches members of a business, optionally limited to a department subtree and a role.
+type Rule struct {
+	TaxNo string `json:"tax_id"`
+	// Department also matches its child departments, nil matches any department.
+	Department *uuid.UUID `json:"department,omitempty"`
+	// Role is a role id or name, empty matches any role.
+	Role string `json:"role,omitempty"`
+}
@@ -2,20 +2,21 @@ package organize
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/inetspa/golib/requests"
 	"github.com/inetspa/golib/web"
 	"github.com/inetspa/oneplatform-sdk-go/identity"
+	"github.com/inetspa/oneplatform-sdk-go/ratelimit"
 	uuid "github.com/satori/go.uuid"
-	log "github.com/sirupsen/logrus"
 	"net/http"
-	"reflect"
+	"strings"
 )
 
 const (
-	apiEndpoint = "https://one.th/api/v2/service/business"
+	apiEndpoint    = "https://one.th/api/v2/service/business"
+	defaultTimeout = 30
 )
 
 func NewClient(username string, password string, clientId string, clientSecret string, refreshToken *string) (OrgClient, error) {
@@ -40,127 +41,209 @@ func NewClient(username string, password string, clientId string, clientSecret s
 	return org, nil
 }
 
+// GetAccounts loads every account of a business, see IterateAccounts for large businesses.
 func (org *OrgClient) GetAccounts(taxNo string) ([]identity.AccountProfile, error) {
 	var accounts []identity.AccountProfile
-	data, err := org.get("/account", taxNo)
-	if err != nil {
-		return accounts, err
-	}
-	for _, v := range data.([]interface{}) {
-		jsonMap, _ := json.Marshal(v.(map[string]interface{}))
-		var a identity.AccountProfile
-		if err := json.Unmarshal(jsonMap, &a); err == nil {
-			accounts = append(accounts, a)
-		} else {
-			log.Errorln("Unmarshal account", err, string(jsonMap))
-		}
+	it := org.IterateAccounts(taxNo, DefaultPageSize)
+	for it.Next() {
+		accounts = append(accounts, it.Account())
 	}
-	return accounts, nil
+	return accounts, it.Err()
 }
 
 func (org *OrgClient) GetDepartments(taxNo string) ([]Department, error) {
 	var dept []Department
-	data, err := org.get("/department", taxNo)
-	if err != nil {
-		return dept, err
-	}
-	for _, v := range data.([]interface{}) {
-		vMap := v.(map[string]interface{})
-		var id uuid.UUID
-		parentDeptId := uuid.Nil
-		if id = uuid.FromStringOrNil(vMap["id"].(string)); id != uuid.Nil {
-			if vMap["parent_dept_id"] != nil {
-				parentDeptId = uuid.FromStringOrNil(vMap["parent_dept_id"].(string))
-			}
-			d := Department{
-				Id:           id,
-				Name:         vMap["dept_name"].(string),
-				ParentDeptId: &parentDeptId,
-			}
-			dept = append(dept, d)
-		} else {
-			log.Errorln("Invalid department", v)
-		}
+	it := org.IterateDepartments(taxNo, DefaultPageSize)
+	for it.Next() {
+		dept = append(dept, it.Department())
 	}
-	return dept, nil
+	return dept, it.Err()
 }
 
 func (org *OrgClient) GetDepartmentAccounts(taxNo string, departmentUid uuid.UUID) ([]identity.Employee, error) {
+	return org.getDepartmentAccounts(context.Background(), taxNo, departmentUid)
+}
+
+func (org *OrgClient) getDepartmentAccounts(ctx context.Context, taxNo string, departmentUid uuid.UUID) ([]identity.Employee, error) {
 	var employee []identity.Employee
-	data, err := org.get(fmt.Sprintf("/department/%s", departmentUid), taxNo)
+	path := fmt.Sprintf("/department/%s", departmentUid)
+	data, err := org.send(ctx, http.MethodGet, path, taxNo, nil)
 	if err != nil {
 		return employee, err
 	}
-	positionCode := map[uuid.UUID]string{}
-	for _, v := range data.(map[string]interface{})["has_role"].([]interface{}) {
-		roleId := uuid.FromStringOrNil(v.(map[string]interface{})["role_id"].(string))
-		positionCode[roleId] = v.(map[string]interface{})["role"].(map[string]interface{})["role_name"].(string)
+	var r departmentAccountsRecord
+	if err := decodeData(path, data, &r); err != nil {
+		return employee, err
 	}
-	for _, v := range data.(map[string]interface{})["has_account"].([]interface{}) {
-		jsonMap, _ := json.Marshal(v)
+	var errs MultiError
+	roles := map[uuid.UUID]Role{}
+	for i, v := range r.HasRole {
+		var role roleRecord
+		if err := json.Unmarshal(v, &role); err != nil {
+			errs = append(errs, &DecodeError{Path: path + "#has_role", Index: i, Raw: v, Err: err})
+			continue
+		}
+		roles[role.RoleId] = role.role()
+	}
+	for i, v := range r.HasAccount {
 		var e identity.Employee
-		if err := json.Unmarshal(jsonMap, &e); err == nil {
-			e.Position = positionCode[e.PositionId]
-			employee = append(employee, e)
-		} else {
-			log.Errorln("Unmarshal employee", err, string(jsonMap))
+		if err := json.Unmarshal(v, &e); err != nil {
+			errs = append(errs, &DecodeError{Path: path + "#has_account", Index: i, Raw: v, Err: err})
+			continue
+		}
+		if role, ok := roles[e.Role.Id]; ok {
+			e.Role = role
 		}
+		employee = append(employee, e)
 	}
-	return employee, nil
+	return employee, errs.errOrNil()
 }
 
 func (org *OrgClient) GetSubordinateDepartmentAccounts(accountId string, taxNo string) ([]TeamMember, error) {
 	var teamMembers []TeamMember
-	rawData, err := org.get(fmt.Sprintf("/account/%s/subordinate-department", accountId), taxNo)
+	path := fmt.Sprintf("/account/%s/subordinate-department", accountId)
+	data, err := org.get(path, taxNo)
 	if err != nil {
 		return teamMembers, err
 	}
-	dataByte, err := json.Marshal(rawData)
-	if err := json.Unmarshal(dataByte, &teamMembers); err != nil {
-		return teamMembers, err
-	}
-	return teamMembers, nil
+	err = decodeList(path, data, func(i int, raw json.RawMessage) error {
+		var t TeamMember
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+		teamMembers = append(teamMembers, t)
+		return nil
+	})
+	return teamMembers, err
 }
 
 func (org *OrgClient) GetHeadDepartmentAccounts(accountId string, taxNo string) ([]HeadDepartment, error) {
 	var headDepart []HeadDepartment
-	rawData, err := org.get(fmt.Sprintf("/account/%s/head-department", accountId), taxNo)
+	path := fmt.Sprintf("/account/%s/head-department", accountId)
+	data, err := org.get(path, taxNo)
 	if err != nil {
 		return headDepart, err
 	}
-	dataByte, err := json.Marshal(rawData)
-	if err := json.Unmarshal(dataByte, &headDepart); err != nil {
-		return headDepart, err
-	}
-	return headDepart, nil
+	err = decodeList(path, data, func(i int, raw json.RawMessage) error {
+		var h HeadDepartment
+		if err := json.Unmarshal(raw, &h); err != nil {
+			return err
+		}
+		headDepart = append(headDepart, h)
+		return nil
+	})
+	return headDepart, err
 }
 
 func (org *OrgClient) SetEndpoint(ep string) {
 	org.ApiEndpoint = ep
 }
 
-func (org *OrgClient) get(uri string, taxNo string) (interface{}, error) {
-	data, _ := json.Marshal(&struct {
-		TaxNo string `json:"tax_id"`
-	}{
-		TaxNo: taxNo,
-	})
+// SetTimeout sets the request timeout in seconds.
+func (org *OrgClient) SetTimeout(seconds int) {
+	org.timeout = seconds
+}
+
+// SetRateLimiter makes every request wait for the limiter first, nil removes it.
+func (org *OrgClient) SetRateLimiter(limiter ratelimit.Limiter) {
+	org.limiter = limiter
+}
+
+func (org *OrgClient) get(uri string, taxNo string) (json.RawMessage, error) {
+	return org.send(context.Background(), http.MethodGet, uri, taxNo, nil)
+}
+
+// send calls the business api, params are sent along with the tax id in the json body.
+func (org *OrgClient) send(ctx context.Context, method string, uri string, taxNo string, params map[string]interface{}) (json.RawMessage, error) {
+	if taxNo != "" {
+		if err := ValidateTaxNo(taxNo); err != nil {
+			return nil, err
+		}
+	}
+	if org.limiter != nil {
+		if err := org.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	} else if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"tax_id": taxNo,
+	}
+	for k, v := range params {
+		body[k] = v
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
 	headers := map[string]string{
 		web.HeaderContentType:   web.MIMEApplicationJSON,
 		web.HeaderAuthorization: fmt.Sprintf("%s %s", org.TokenType, org.AccessToken),
 	}
-	r, err := requests.Get(org.url(uri), headers, bytes.NewBuffer(data), 30)
+	timeout := org.timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	r, err := requests.Request(method, org.url(uri), headers, bytes.NewBuffer(data), timeout)
 	if err != nil {
 		return nil, err
 	}
-	if r.Code != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("server return code %d %s", r.Code, string(r.Body)))
+	if r.Code != http.StatusOK && r.Code != http.StatusCreated {
+		return nil, &ApiError{StatusCode: r.Code, Message: string(r.Body)}
 	}
 	var orgApiResult OrgApiResult
 	if err := json.Unmarshal(r.Body, &orgApiResult); err != nil {
 		return nil, err
 	}
-	return reflect.ValueOf(orgApiResult.Data).Interface(), nil
+	if orgApiResult.failed() {
+		return nil, &ApiError{
+			StatusCode: r.Code,
+			Code:       orgApiResult.Code,
+			Result:     orgApiResult.Result,
+			Message:    fmt.Sprint(orgApiResult.Error),
+		}
+	}
+	return orgApiResult.Data, nil
+}
+
+// failed reports an error envelope, either an explicit fail result or an error message without success.
+func (r OrgApiResult) failed() bool {
+	if strings.EqualFold(r.Result, "fail") {
+		return true
+	}
+	if strings.EqualFold(r.Result, "success") || r.Error == nil {
+		return false
+	}
+	msg, ok := r.Error.(string)
+	return !ok || msg != ""
+}
+
+// decodeData unmarshals the data field of a response, null data leaves v untouched.
+func decodeData(path string, data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return &DecodeError{Path: path, Index: -1, Raw: data, Err: err}
+	}
+	return nil
+}
+
+// decodeList unmarshals a list in the data field of a response and passes each item to decode.
+// Items decode fails on are skipped and returned together as a MultiError.
+func decodeList(path string, data json.RawMessage, decode func(i int, raw json.RawMessage) error) error {
+	var items []json.RawMessage
+	if err := decodeData(path, data, &items); err != nil {
+		return err
+	}
+	var errs MultiError
+	for i, raw := range items {
+		if err := decode(i, raw); err != nil {
+			errs = append(errs, &DecodeError{Path: path, Index: i, Raw: raw, Err: err})
+		}
+	}
+	return errs.errOrNil()
 }
 
 func (org *OrgClient) url(path string) string {
@@ -0,0 +1,85 @@
+package organize_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/organize"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const taxNo = "0105536092641"
+
+var deptId = uuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+// serve answers every request with body and returns a client using it.
+func serve(t *testing.T, body string) *organize.OrgClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	org := &organize.OrgClient{}
+	org.SetEndpoint(srv.URL)
+	return org
+}
+
+func TestGetDepartmentsNullName(t *testing.T) {
+	org := serve(t, `{"result":"Success","data":[
+		{"id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","dept_name":null},
+		{"id":"6ba7b811-9dad-11d1-80b4-00c04fd430c8","dept_name":"HR"}
+	]}`)
+	departments, err := org.GetDepartments(taxNo)
+	if !organize.IsPartial(err) {
+		t.Fatalf("err = %v, want a partial error", err)
+	}
+	if len(departments) != 1 || departments[0].Name != "HR" {
+		t.Fatalf("departments = %+v, want only HR", departments)
+	}
+}
+
+func TestGetDepartmentAccountsMissingHasRole(t *testing.T) {
+	org := serve(t, `{"result":"Success","data":{"has_account":[
+		{"account_id":"1","role_id":"6ba7b812-9dad-11d1-80b4-00c04fd430c8"}
+	]}}`)
+	employees, err := org.GetDepartmentAccounts(taxNo, deptId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(employees) != 1 || employees[0].AccountId != "1" {
+		t.Fatalf("employees = %+v, want account 1", employees)
+	}
+}
+
+func TestGetDepartmentAccountsMalformedItem(t *testing.T) {
+	org := serve(t, `{"result":"Success","data":{"has_role":[42],"has_account":[
+		{"account_id":"1"},
+		{"account_id":7}
+	]}}`)
+	employees, err := org.GetDepartmentAccounts(taxNo, deptId)
+	var errs organize.MultiError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("err = %v, want two decode errors", err)
+	}
+	if len(employees) != 1 {
+		t.Fatalf("employees = %+v, want the valid account", employees)
+	}
+}
+
+func TestGetDepartmentAccountsErrorEnvelope(t *testing.T) {
+	org := serve(t, `{"result":"Fail","errorMessage":"department not found","data":null}`)
+	_, err := org.GetDepartmentAccounts(taxNo, deptId)
+	var apiErr *organize.ApiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want an ApiError", err)
+	}
+}
+
+func TestGetRolesNullData(t *testing.T) {
+	org := serve(t, `{"result":"Success","data":null}`)
+	roles, err := org.GetRoles(taxNo)
+	if err != nil || len(roles) != 0 {
+		t.Fatalf("roles = %+v, err = %v, want none", roles, err)
+	}
+}
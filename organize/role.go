@@ -0,0 +1,81 @@
+package organize
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+)
+
+// GetRoles lists every role defined in a business.
+func (org *OrgClient) GetRoles(taxNo string) ([]Role, error) {
+	var roles []Role
+	data, err := org.get("/role", taxNo)
+	if err != nil {
+		return roles, err
+	}
+	err = decodeList("/role", data, func(i int, raw json.RawMessage) error {
+		var r Role
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		roles = append(roles, r)
+		return nil
+	})
+	return roles, err
+}
+
+// GetRoleAccounts lists the accounts holding a role in any department of a business.
+func (org *OrgClient) GetRoleAccounts(taxNo string, roleUid uuid.UUID) ([]identity.Employee, error) {
+	var employee []identity.Employee
+	path := fmt.Sprintf("/role/%s/account", roleUid)
+	data, err := org.get(path, taxNo)
+	if err != nil {
+		return employee, err
+	}
+	err = decodeList(path, data, func(i int, raw json.RawMessage) error {
+		var e identity.Employee
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return err
+		}
+		if e.Role.Id == uuid.Nil {
+			e.Role.Id = roleUid
+		}
+		employee = append(employee, e)
+		return nil
+	})
+	return employee, err
+}
+
+// GetAccountRoles lists the roles of one account across the departments it belongs to.
+func (org *OrgClient) GetAccountRoles(accountId string, taxNo string) ([]AccountRole, error) {
+	var roles []AccountRole
+	path := fmt.Sprintf("/account/%s/role", accountId)
+	data, err := org.get(path, taxNo)
+	if err != nil {
+		return roles, err
+	}
+	err = decodeList(path, data, func(i int, raw json.RawMessage) error {
+		var r AccountRole
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+		if r.DepartmentId == uuid.Nil && r.Department != nil {
+			r.DepartmentId = r.Department.Id
+		}
+		roles = append(roles, r)
+		return nil
+	})
+	return roles, err
+}
+
+func (r roleRecord) role() Role {
+	role := Role{Id: r.RoleId}
+	if r.Role != nil {
+		role = *r.Role
+		if role.Id == uuid.Nil {
+			role.Id = r.RoleId
+		}
+	}
+	return role
+}
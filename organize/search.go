@@ -0,0 +1,263 @@
+package organize
+
+import (
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchIndex is an in-process index over the accounts of a snapshot. It matches names, emails,
+// employee ids and phone numbers by exact, prefix, substring and fuzzy comparison. Thai text is
+// matched without tone marks and with its vowel marks in a canonical order, English ignores case
+// and phone numbers are compared by their digits only.
+type SearchIndex struct {
+	entries []searchEntry
+}
+
+type searchEntry struct {
+	account identity.AccountProfile
+	terms   []searchTerm
+}
+
+type searchTerm struct {
+	field  string
+	value  string
+	weight float64
+	phone  bool
+}
+
+const (
+	exactScore     = 1.0
+	prefixScore    = 0.8
+	substringScore = 0.5
+	fuzzyScore     = 0.4
+)
+
+func NewSearchIndex(s Snapshot) *SearchIndex {
+	employeeIds := map[string][]string{}
+	for _, m := range s.Memberships {
+		if m.EmployeeId != "" {
+			employeeIds[m.AccountId] = append(employeeIds[m.AccountId], m.EmployeeId)
+		}
+	}
+	idx := &SearchIndex{}
+	for _, a := range s.Accounts {
+		e := searchEntry{account: a}
+		e.addText("first_name_th", a.FirstNameTH, 1)
+		e.addText("last_name_th", a.LastNameTH, 1)
+		e.addText("first_name_eng", a.FirstNameENG, 1)
+		e.addText("last_name_eng", a.LastNameENG, 1)
+		e.addText("email", a.ThaiEmail1, 0.9)
+		e.addText("email", a.ThaiEmail2, 0.9)
+		for _, m := range a.Email {
+			e.addText("email", m.Email, 0.9)
+		}
+		if a.Employee != nil {
+			e.addText("employee_id", a.Employee.EmployeeId, 1)
+			e.addText("email", a.Employee.Email, 0.9)
+		}
+		for _, id := range employeeIds[a.ID] {
+			e.addText("employee_id", id, 1)
+		}
+		e.addPhone(a.TelephoneNumber)
+		for _, m := range a.Mobile {
+			e.addPhone(m.MobileNumber)
+		}
+		idx.entries = append(idx.entries, e)
+	}
+	return idx
+}
+
+// Search returns accounts matching every word of the query, best match first. A limit of zero
+// or less returns all matches.
+func (idx *SearchIndex) Search(query string, limit int) []SearchResult {
+	words := strings.Fields(NormalizeSearchText(query))
+	if looksLikePhone(query) {
+		words = []string{normalizeMobile(query)}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	var results []SearchResult
+	for _, e := range idx.entries {
+		total := 0.0
+		field := ""
+		matched := true
+		for _, w := range words {
+			score, f := e.match(w)
+			if score == 0 {
+				matched = false
+				break
+			}
+			if field == "" {
+				field = f
+			}
+			total += score
+		}
+		if matched {
+			results = append(results, SearchResult{Account: e.account, Field: field, Score: total / float64(len(words))})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Account.FirstNameTH+results[i].Account.LastNameTH < results[j].Account.FirstNameTH+results[j].Account.LastNameTH
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (e *searchEntry) addText(field string, value string, weight float64) {
+	for _, v := range strings.Fields(NormalizeSearchText(value)) {
+		e.terms = append(e.terms, searchTerm{field: field, value: v, weight: weight})
+	}
+}
+
+func (e *searchEntry) addPhone(value string) {
+	if phone := normalizeMobile(value); phone != "" {
+		e.terms = append(e.terms, searchTerm{field: "phone", value: phone, weight: 0.9, phone: true})
+	}
+}
+
+// match scores the best term for one query word.
+func (e *searchEntry) match(word string) (float64, string) {
+	best, field := 0.0, ""
+	phone := normalizeMobile(word)
+	isPhone := looksLikePhone(word)
+	for _, t := range e.terms {
+		var score float64
+		if t.phone {
+			if !isPhone {
+				continue
+			}
+			switch {
+			case t.value == phone:
+				score = exactScore
+			case strings.HasPrefix(t.value, phone), strings.HasSuffix(t.value, phone):
+				score = prefixScore
+			case strings.Contains(t.value, phone):
+				score = substringScore
+			}
+		} else {
+			switch {
+			case t.value == word:
+				score = exactScore
+			case strings.HasPrefix(t.value, word):
+				score = prefixScore
+			case len([]rune(word)) >= 3 && strings.Contains(t.value, word):
+				score = substringScore
+			default:
+				if d := levenshtein(t.value, word); d > 0 && d <= maxEdits(word) {
+					score = fuzzyScore / float64(d)
+				}
+			}
+		}
+		if score *= t.weight; score > best {
+			best, field = score, t.field
+		}
+	}
+	return best, field
+}
+
+// NormalizeSearchText lower cases English and puts Thai text in a canonical form: tone marks are
+// removed, a doubled sara e becomes sara ae, nikhahit followed by sara aa becomes sara am and
+// vowel marks above and below a consonant are sorted.
+func NormalizeSearchText(s string) string {
+	var out []rune
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= '\u0e48' && r <= '\u0e4b', r == '\u200b', r == '\ufeff':
+			// tone marks, zero width space and byte order mark
+			continue
+		case r == '\u0e40' && len(out) > 0 && out[len(out)-1] == '\u0e40':
+			out[len(out)-1] = '\u0e41'
+			continue
+		case r == '\u0e32' && len(out) > 0 && out[len(out)-1] == '\u0e4d':
+			out[len(out)-1] = '\u0e33'
+			continue
+		case unicode.IsSpace(r):
+			r = ' '
+		}
+		out = append(out, r)
+		for i := len(out) - 1; i > 0 && thaiMarkRank(out[i]) > 0 && thaiMarkRank(out[i-1]) > thaiMarkRank(out[i]); i-- {
+			out[i], out[i-1] = out[i-1], out[i]
+		}
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// thaiMarkRank orders combining Thai marks, zero means the rune is not a combining mark.
+func thaiMarkRank(r rune) int {
+	switch {
+	case r >= '\u0e38' && r <= '\u0e3a':
+		// sara u, sara uu and phinthu below the consonant
+		return 1
+	case r == '\u0e31', r >= '\u0e34' && r <= '\u0e37', r == '\u0e47':
+		// mai han akat, sara i to sara uee and maitaikhu above the consonant
+		return 2
+	case r == '\u0e4c', r == '\u0e4d', r == '\u0e4e':
+		// thanthakhat, nikhahit and yamakkan
+		return 3
+	}
+	return 0
+}
+
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// looksLikePhone reports whether s only holds digits and phone separators, with at least three digits.
+func looksLikePhone(s string) bool {
+	digits := 0
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case strings.ContainsRune("+-(). ", c):
+		default:
+			return false
+		}
+	}
+	return digits >= 3
+}
+
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,250 @@
+package organize
+
+import (
+	"encoding/json"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	uuid "github.com/satori/go.uuid"
+	"io"
+	"sort"
+	"time"
+)
+
+// TakeSnapshot loads accounts, departments, memberships and heads of a business in one go.
+// Malformed items are skipped and reported through a MultiError next to the snapshot.
+func (org *OrgClient) TakeSnapshot(taxNo string) (Snapshot, error) {
+	snapshot := Snapshot{
+		TaxNo:   taxNo,
+		TakenAt: time.Now(),
+	}
+	var errs MultiError
+	accounts, err := org.GetAccounts(taxNo)
+	if err := errs.merge(err); err != nil {
+		return snapshot, err
+	}
+	departments, err := org.GetDepartments(taxNo)
+	if err := errs.merge(err); err != nil {
+		return snapshot, err
+	}
+	members, heads, err := org.getDepartmentMembers(taxNo, departments)
+	if err := errs.merge(err); err != nil {
+		return snapshot, err
+	}
+	snapshot.Accounts = accounts
+	snapshot.Departments = departments
+	for _, d := range departments {
+		for _, e := range members[d.Id] {
+			snapshot.Memberships = append(snapshot.Memberships, Membership{
+				DepartmentId: d.Id,
+				AccountId:    e.AccountId,
+				EmployeeId:   e.EmployeeId,
+				Email:        e.Email,
+				Role:         e.Role,
+			})
+		}
+		for _, e := range heads[d.Id] {
+			snapshot.Heads = append(snapshot.Heads, DepartmentHead{
+				DepartmentId: d.Id,
+				AccountId:    e.AccountId,
+			})
+		}
+	}
+	return snapshot, errs.errOrNil()
+}
+
+func LoadSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+func (s Snapshot) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Chart rebuilds the org chart from the snapshot without calling the API.
+func (s Snapshot) Chart() OrgChart {
+	accounts := s.accountIndex()
+	members := map[uuid.UUID][]identity.Employee{}
+	for _, m := range s.Memberships {
+		members[m.DepartmentId] = append(members[m.DepartmentId], m.employee(accounts))
+	}
+	heads := map[uuid.UUID][]identity.Employee{}
+	for _, h := range s.Heads {
+		e := identity.Employee{AccountId: h.AccountId}
+		for _, m := range s.Memberships {
+			if m.DepartmentId == h.DepartmentId && m.AccountId == h.AccountId {
+				e = m.employee(accounts)
+				break
+			}
+		}
+		if e.Account == nil {
+			if a, ok := accounts[h.AccountId]; ok {
+				e.Account = &a
+			}
+		}
+		heads[h.DepartmentId] = append(heads[h.DepartmentId], e)
+	}
+	return NewOrgChart(s.TaxNo, s.Departments, members, heads)
+}
+
+// Diff compares two snapshots of the same business and returns what changed from old to new.
+// Department events come first, followed by account events ordered by account id.
+func Diff(old Snapshot, new Snapshot) []Event {
+	var events []Event
+	oldDepts := map[uuid.UUID]Department{}
+	for _, d := range old.Departments {
+		oldDepts[d.Id] = d
+	}
+	newDepts := map[uuid.UUID]Department{}
+	for _, d := range new.Departments {
+		newDepts[d.Id] = d
+	}
+	for _, d := range sortedDepartments(new.Departments) {
+		o, ok := oldDepts[d.Id]
+		if !ok {
+			events = append(events, Event{Type: DepartmentCreated, DepartmentId: d.Id, To: d.Name})
+			continue
+		}
+		if o.Name != d.Name {
+			events = append(events, Event{Type: DepartmentRenamed, DepartmentId: d.Id, From: o.Name, To: d.Name})
+		}
+		if parentOf(o) != parentOf(d) {
+			events = append(events, Event{Type: DepartmentReparented, DepartmentId: d.Id, From: parentName(oldDepts, o), To: parentName(newDepts, d)})
+		}
+	}
+	for _, d := range sortedDepartments(old.Departments) {
+		if _, ok := newDepts[d.Id]; !ok {
+			events = append(events, Event{Type: DepartmentDeleted, DepartmentId: d.Id, From: d.Name})
+		}
+	}
+
+	oldAccounts := old.accountIndex()
+	newAccounts := new.accountIndex()
+	oldMembers := old.membershipIndex()
+	newMembers := new.membershipIndex()
+	var accountIds []string
+	for id := range oldAccounts {
+		accountIds = append(accountIds, id)
+	}
+	for id := range newAccounts {
+		if _, ok := oldAccounts[id]; !ok {
+			accountIds = append(accountIds, id)
+		}
+	}
+	sort.Strings(accountIds)
+	for _, id := range accountIds {
+		_, inOld := oldAccounts[id]
+		_, inNew := newAccounts[id]
+		switch {
+		case !inOld:
+			events = append(events, Event{Type: AccountJoined, AccountId: id})
+			continue
+		case !inNew:
+			events = append(events, Event{Type: AccountLeft, AccountId: id})
+			continue
+		}
+		var removed, added []Membership
+		for _, m := range oldMembers[id] {
+			if n, ok := findMembership(newMembers[id], m.DepartmentId); !ok {
+				removed = append(removed, m)
+			} else if !sameRole(n.Role, m.Role) {
+				events = append(events, Event{Type: PositionChanged, AccountId: id, DepartmentId: m.DepartmentId, From: m.Role.Name, To: n.Role.Name})
+			}
+		}
+		for _, m := range newMembers[id] {
+			if _, ok := findMembership(oldMembers[id], m.DepartmentId); !ok {
+				added = append(added, m)
+			}
+		}
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			e := Event{Type: AccountMoved, AccountId: id}
+			if i < len(removed) {
+				e.From = departmentName(oldDepts, removed[i].DepartmentId)
+			}
+			if i < len(added) {
+				e.DepartmentId = added[i].DepartmentId
+				e.To = departmentName(newDepts, added[i].DepartmentId)
+			} else {
+				e.DepartmentId = removed[i].DepartmentId
+			}
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (s Snapshot) accountIndex() map[string]identity.AccountProfile {
+	accounts := map[string]identity.AccountProfile{}
+	for _, a := range s.Accounts {
+		accounts[a.ID] = a
+	}
+	return accounts
+}
+
+func (s Snapshot) membershipIndex() map[string][]Membership {
+	members := map[string][]Membership{}
+	for _, m := range s.Memberships {
+		members[m.AccountId] = append(members[m.AccountId], m)
+	}
+	for _, ms := range members {
+		sort.Slice(ms, func(i, j int) bool {
+			return ms[i].DepartmentId.String() < ms[j].DepartmentId.String()
+		})
+	}
+	return members
+}
+
+func (m Membership) employee(accounts map[string]identity.AccountProfile) identity.Employee {
+	e := identity.Employee{
+		AccountId:  m.AccountId,
+		EmployeeId: m.EmployeeId,
+		Email:      m.Email,
+		Role:       m.Role,
+	}
+	if a, ok := accounts[m.AccountId]; ok {
+		e.Account = &a
+	}
+	return e
+}
+
+func findMembership(members []Membership, departmentId uuid.UUID) (Membership, bool) {
+	for _, m := range members {
+		if m.DepartmentId == departmentId {
+			return m, true
+		}
+	}
+	return Membership{}, false
+}
+
+// sameRole compares roles by id, or by name when neither has an id. A renamed role or a changed
+// level is not a position change.
+func sameRole(a Role, b Role) bool {
+	if a.Id == uuid.Nil && b.Id == uuid.Nil {
+		return a.Name == b.Name
+	}
+	return a.Id == b.Id
+}
+
+func sortedDepartments(departments []Department) []Department {
+	sorted := append([]Department{}, departments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func departmentName(departments map[uuid.UUID]Department, id uuid.UUID) string {
+	if d, ok := departments[id]; ok {
+		return d.Name
+	}
+	return id.String()
+}
+
+func parentName(departments map[uuid.UUID]Department, d Department) string {
+	if parentOf(d) == uuid.Nil {
+		return ""
+	}
+	return departmentName(departments, parentOf(d))
+}
@@ -0,0 +1,193 @@
+package organize
+
+import (
+	"context"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"strings"
+)
+
+// DryRun previews a write operation, the returned Change describes the request without sending it.
+func DryRun() WriteOption {
+	return func(o *writeOptions) {
+		o.dryRun = true
+	}
+}
+
+func (org *OrgClient) CreateDepartment(taxNo string, name string, parentUid *uuid.UUID, opts ...WriteOption) (Change, error) {
+	if strings.TrimSpace(name) == "" {
+		return Change{}, fmt.Errorf("%w: department name required", ErrInvalidChange)
+	}
+	params := map[string]interface{}{
+		"dept_name": name,
+	}
+	if parentUid != nil && *parentUid != uuid.Nil {
+		params["parent_dept_id"] = parentUid.String()
+	}
+	c := Change{
+		Action:  CreateDepartmentChange,
+		Method:  http.MethodPost,
+		Path:    "/department",
+		Payload: params,
+	}
+	var created departmentRecord
+	c, err := org.apply(taxNo, c, &created, opts)
+	if err == nil && c.Applied && created.Id != nil {
+		c.DepartmentId = *created.Id
+	}
+	return c, err
+}
+
+func (org *OrgClient) RenameDepartment(taxNo string, departmentUid uuid.UUID, name string, opts ...WriteOption) (Change, error) {
+	if strings.TrimSpace(name) == "" {
+		return Change{}, fmt.Errorf("%w: department name required", ErrInvalidChange)
+	}
+	c := Change{
+		Action:       RenameDepartmentChange,
+		Method:       http.MethodPut,
+		Path:         fmt.Sprintf("/department/%s", departmentUid),
+		DepartmentId: departmentUid,
+		Payload: map[string]interface{}{
+			"dept_name": name,
+		},
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+// MoveDepartment re-parents a department, a nil parent makes it a root department.
+func (org *OrgClient) MoveDepartment(taxNo string, departmentUid uuid.UUID, parentUid *uuid.UUID, opts ...WriteOption) (Change, error) {
+	var parent interface{}
+	if parentUid != nil && *parentUid != uuid.Nil {
+		if *parentUid == departmentUid {
+			return Change{}, fmt.Errorf("%w: department cannot be its own parent", ErrInvalidChange)
+		}
+		parent = parentUid.String()
+	}
+	c := Change{
+		Action:       MoveDepartmentChange,
+		Method:       http.MethodPut,
+		Path:         fmt.Sprintf("/department/%s", departmentUid),
+		DepartmentId: departmentUid,
+		Payload: map[string]interface{}{
+			"parent_dept_id": parent,
+		},
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+func (org *OrgClient) DeleteDepartment(taxNo string, departmentUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	c := Change{
+		Action:       DeleteDepartmentChange,
+		Method:       http.MethodDelete,
+		Path:         fmt.Sprintf("/department/%s", departmentUid),
+		DepartmentId: departmentUid,
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+func (org *OrgClient) AddDepartmentAccount(taxNo string, departmentUid uuid.UUID, accountId string, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	if accountId == "" {
+		return Change{}, fmt.Errorf("%w: account id required", ErrInvalidChange)
+	}
+	if roleUid == uuid.Nil {
+		return Change{}, fmt.Errorf("%w: role required", ErrInvalidChange)
+	}
+	c := Change{
+		Action:       AddAccountChange,
+		Method:       http.MethodPost,
+		Path:         fmt.Sprintf("/department/%s/account", departmentUid),
+		DepartmentId: departmentUid,
+		AccountId:    accountId,
+		Payload: map[string]interface{}{
+			"account_id": accountId,
+			"role_id":    roleUid.String(),
+		},
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+func (org *OrgClient) RemoveDepartmentAccount(taxNo string, departmentUid uuid.UUID, accountId string, opts ...WriteOption) (Change, error) {
+	if accountId == "" {
+		return Change{}, fmt.Errorf("%w: account id required", ErrInvalidChange)
+	}
+	c := Change{
+		Action:       RemoveAccountChange,
+		Method:       http.MethodDelete,
+		Path:         fmt.Sprintf("/department/%s/account/%s", departmentUid, accountId),
+		DepartmentId: departmentUid,
+		AccountId:    accountId,
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+// MoveDepartmentAccount moves an account from one department to another with the given role.
+func (org *OrgClient) MoveDepartmentAccount(taxNo string, accountId string, fromDepartmentUid uuid.UUID, toDepartmentUid uuid.UUID, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	if accountId == "" {
+		return Change{}, fmt.Errorf("%w: account id required", ErrInvalidChange)
+	}
+	if roleUid == uuid.Nil {
+		return Change{}, fmt.Errorf("%w: role required", ErrInvalidChange)
+	}
+	c := Change{
+		Action:       MoveAccountChange,
+		Method:       http.MethodPut,
+		Path:         fmt.Sprintf("/account/%s/department", accountId),
+		DepartmentId: toDepartmentUid,
+		AccountId:    accountId,
+		Payload: map[string]interface{}{
+			"from_dept_id": fromDepartmentUid.String(),
+			"dept_id":      toDepartmentUid.String(),
+			"role_id":      roleUid.String(),
+		},
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+// SetDepartmentAccountRole changes the role an account holds in a department.
+func (org *OrgClient) SetDepartmentAccountRole(taxNo string, departmentUid uuid.UUID, accountId string, roleUid uuid.UUID, opts ...WriteOption) (Change, error) {
+	if accountId == "" {
+		return Change{}, fmt.Errorf("%w: account id required", ErrInvalidChange)
+	}
+	if roleUid == uuid.Nil {
+		return Change{}, fmt.Errorf("%w: role required", ErrInvalidChange)
+	}
+	c := Change{
+		Action:       ChangeRoleChange,
+		Method:       http.MethodPut,
+		Path:         fmt.Sprintf("/department/%s/account/%s", departmentUid, accountId),
+		DepartmentId: departmentUid,
+		AccountId:    accountId,
+		Payload: map[string]interface{}{
+			"role_id": roleUid.String(),
+		},
+	}
+	return org.apply(taxNo, c, nil, opts)
+}
+
+// apply sends the change unless it is a dry run, result receives the response data when not nil.
+func (org *OrgClient) apply(taxNo string, c Change, result interface{}, opts []WriteOption) (Change, error) {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if taxNo != "" {
+		if err := ValidateTaxNo(taxNo); err != nil {
+			return c, err
+		}
+	}
+	if o.dryRun {
+		return c, nil
+	}
+	data, err := org.send(context.Background(), c.Method, c.Path, taxNo, c.Payload)
+	if err != nil {
+		return c, err
+	}
+	c.Applied = true
+	if result != nil {
+		if err := decodeData(c.Path, data, result); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
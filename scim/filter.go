@@ -0,0 +1,162 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filter is a parsed SCIM filter. Supported are the eq, ne, co, sw, ew and pr operators combined
+// with and/or, without grouping. Values are compared case insensitively.
+type filter [][]comparison
+
+type comparison struct {
+	attr  string
+	op    string
+	value string
+}
+
+// attributes gives the values of an attribute path of a resource, lower case.
+type attributes func(attr string) []string
+
+var errInvalidFilter = errors.New("invalid filter")
+
+func parseFilter(s string) (filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	var f filter
+	var and []comparison
+	for i := 0; i < len(tokens); {
+		if len(tokens)-i < 2 {
+			return nil, fmt.Errorf("%w: incomplete expression", errInvalidFilter)
+		}
+		c := comparison{attr: strings.ToLower(tokens[i]), op: strings.ToLower(tokens[i+1])}
+		i += 2
+		switch c.op {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("%w: missing value for %s", errInvalidFilter, c.op)
+			}
+			c.value = strings.ToLower(tokens[i])
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unsupported operator %q", errInvalidFilter, c.op)
+		}
+		and = append(and, c)
+		if i == len(tokens) {
+			break
+		}
+		switch strings.ToLower(tokens[i]) {
+		case "and":
+		case "or":
+			f = append(f, and)
+			and = nil
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", errInvalidFilter, tokens[i])
+		}
+		i++
+		if i == len(tokens) {
+			return nil, fmt.Errorf("%w: dangling logical operator", errInvalidFilter)
+		}
+	}
+	return append(f, and), nil
+}
+
+func (f filter) match(attrs attributes) bool {
+	if len(f) == 0 {
+		return true
+	}
+	for _, and := range f {
+		ok := true
+		for _, c := range and {
+			if !c.match(attrs(c.attr)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparison) match(values []string) bool {
+	if c.op == "ne" {
+		for _, v := range values {
+			if v == c.value {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		switch c.op {
+		case "pr":
+			if v != "" {
+				return true
+			}
+		case "eq":
+			if v == c.value {
+				return true
+			}
+		case "co":
+			if strings.Contains(v, c.value) {
+				return true
+			}
+		case "sw":
+			if strings.HasPrefix(v, c.value) {
+				return true
+			}
+		case "ew":
+			if strings.HasSuffix(v, c.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tokenize splits a filter on spaces, keeping quoted strings whole and unquoted.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			return nil, fmt.Errorf("%w: grouping is not supported", errInvalidFilter)
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", errInvalidFilter)
+			}
+			v, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidFilter, err)
+			}
+			tokens = append(tokens, v)
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && s[j] != ' ' {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,451 @@
+package scim
+
+import (
+	"encoding/json"
+	"github.com/inetspa/oneplatform-sdk-go/identity"
+	"github.com/inetspa/oneplatform-sdk-go/organize"
+	uuid "github.com/satori/go.uuid"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRefreshInterval = 5 * time.Minute
+	DefaultRetryInterval   = 30 * time.Second
+	DefaultPageSize        = 100
+)
+
+// Directory provides the organization data served over SCIM, *organize.OrgClient implements it.
+type Directory interface {
+	TakeSnapshot(taxNo string) (organize.Snapshot, error)
+}
+
+// Handler serves a read-only SCIM 2.0 api of one business: accounts are Users and departments are
+// Groups with their members. It answers on /Users, /Groups, /ServiceProviderConfig and /ResourceTypes,
+// mount it with http.StripPrefix and protect it with identity.BearerAuth:
+//
+//	h := scim.NewHandler(&org, taxNo, scim.Options{})
+//	http.Handle("/scim/v2/", http.StripPrefix("/scim/v2", identity.BearerAuth(identity.StaticToken(token))(h)))
+type Handler struct {
+	directory Directory
+	taxNo     string
+	options   Options
+
+	mu         sync.Mutex
+	users      []User
+	groups     []Group
+	loadedAt   time.Time
+	failedAt   time.Time
+	lastErr    error
+	refreshing bool
+}
+
+type Options struct {
+	// RefreshInterval is how long a snapshot of the business is served before it is reloaded.
+	RefreshInterval time.Duration
+	// RetryInterval is how long to wait after a failed load before trying again.
+	RetryInterval time.Duration
+	// PageSize is the count used when the client does not ask for one, and the most returned at once.
+	PageSize int
+}
+
+func NewHandler(directory Directory, taxNo string, options Options) *Handler {
+	if options.RefreshInterval <= 0 {
+		options.RefreshInterval = DefaultRefreshInterval
+	}
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = DefaultRetryInterval
+	}
+	if options.PageSize <= 0 {
+		options.PageSize = DefaultPageSize
+	}
+	return &Handler{directory: directory, taxNo: taxNo, options: options}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) > 2 {
+		writeError(w, http.StatusNotFound, "", "resource not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "", "directory is read-only")
+		return
+	}
+	switch segments[0] {
+	case "ServiceProviderConfig":
+		writeJSON(w, http.StatusOK, serviceProviderConfig(h.options.PageSize))
+		return
+	case "ResourceTypes":
+		writeJSON(w, http.StatusOK, resourceTypes())
+		return
+	case "Users", "Groups":
+	default:
+		writeError(w, http.StatusNotFound, "", "resource not found")
+		return
+	}
+
+	users, groups, err := h.load()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "", err.Error())
+		return
+	}
+	if len(segments) == 2 {
+		if segments[0] == "Users" {
+			for _, u := range users {
+				if u.Id == segments[1] {
+					writeJSON(w, http.StatusOK, u)
+					return
+				}
+			}
+		} else {
+			for _, g := range groups {
+				if g.Id == segments[1] {
+					writeJSON(w, http.StatusOK, g)
+					return
+				}
+			}
+		}
+		writeError(w, http.StatusNotFound, "", "resource not found")
+		return
+	}
+
+	q := r.URL.Query()
+	f, err := parseFilter(q.Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+		return
+	}
+	startIndex, err := queryInt(q.Get("startIndex"), 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", "startIndex must be a number")
+		return
+	}
+	count, err := queryInt(q.Get("count"), h.options.PageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalidValue", "count must be a number")
+		return
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > h.options.PageSize {
+		count = h.options.PageSize
+	}
+
+	var matched []interface{}
+	if segments[0] == "Users" {
+		for _, u := range users {
+			if f.match(u.attributes) {
+				matched = append(matched, u)
+			}
+		}
+	} else {
+		for _, g := range groups {
+			if f.match(g.attributes) {
+				matched = append(matched, g)
+			}
+		}
+	}
+	page := []interface{}{}
+	if startIndex <= len(matched) {
+		if rest := len(matched) - (startIndex - 1); count > rest {
+			count = rest
+		}
+		page = matched[startIndex-1 : startIndex-1+count]
+	}
+	writeJSON(w, http.StatusOK, ListResponse{
+		Schemas:      []string{listSchema},
+		TotalResults: len(matched),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+// load returns the users and groups. The first load is made in the request, later ones in the
+// background once the snapshot is older than the refresh interval while the previous data is
+// served. A failed load is not tried again before the retry interval.
+func (h *Handler) load() ([]User, []Group, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	retry := h.failedAt.IsZero() || time.Since(h.failedAt) >= h.options.RetryInterval
+	if h.loadedAt.IsZero() {
+		if !retry {
+			return nil, nil, h.lastErr
+		}
+		snapshot, err := h.directory.TakeSnapshot(h.taxNo)
+		h.store(snapshot, err)
+		if h.loadedAt.IsZero() {
+			return nil, nil, err
+		}
+		return h.users, h.groups, nil
+	}
+	if retry && !h.refreshing && time.Since(h.loadedAt) >= h.options.RefreshInterval {
+		h.refreshing = true
+		go func() {
+			snapshot, err := h.directory.TakeSnapshot(h.taxNo)
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.refreshing = false
+			h.store(snapshot, err)
+		}()
+	}
+	return h.users, h.groups, nil
+}
+
+// store keeps a loaded snapshot, or records the failure and keeps the previous data.
+func (h *Handler) store(snapshot organize.Snapshot, err error) {
+	if err != nil && !organize.IsPartial(err) {
+		h.failedAt = time.Now()
+		h.lastErr = err
+		return
+	}
+	h.users, h.groups = resources(snapshot)
+	h.loadedAt = time.Now()
+	h.failedAt = time.Time{}
+	h.lastErr = nil
+}
+
+// Refresh drops the loaded snapshot so the next request reloads it.
+func (h *Handler) Refresh() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loadedAt = time.Time{}
+	h.failedAt = time.Time{}
+}
+
+func resources(s organize.Snapshot) ([]User, []Group) {
+	lastModified := s.TakenAt.UTC().Format(time.RFC3339)
+	departments := map[uuid.UUID]organize.Department{}
+	for _, d := range s.Departments {
+		departments[d.Id] = d
+	}
+	memberships := map[string][]organize.Membership{}
+	for _, m := range s.Memberships {
+		memberships[m.AccountId] = append(memberships[m.AccountId], m)
+	}
+
+	var users []User
+	names := map[string]string{}
+	for _, a := range s.Accounts {
+		u := userFromAccount(a, memberships[a.ID])
+		for _, m := range memberships[a.ID] {
+			u.Groups = append(u.Groups, Reference{
+				Value:   m.DepartmentId.String(),
+				Display: departments[m.DepartmentId].Name,
+				Type:    "direct",
+				Ref:     "../Groups/" + m.DepartmentId.String(),
+			})
+		}
+		u.Meta = Meta{ResourceType: "User", LastModified: lastModified, Location: "Users/" + u.Id}
+		names[a.ID] = u.DisplayName
+		users = append(users, u)
+	}
+
+	var groups []Group
+	for _, d := range s.Departments {
+		g := Group{
+			Schemas:     []string{groupSchema},
+			Id:          d.Id.String(),
+			DisplayName: d.Name,
+			Members:     []Reference{},
+			Meta:        Meta{ResourceType: "Group", LastModified: lastModified, Location: "Groups/" + d.Id.String()},
+		}
+		for _, m := range s.Memberships {
+			if m.DepartmentId == d.Id {
+				g.Members = append(g.Members, Reference{
+					Value:   m.AccountId,
+					Display: names[m.AccountId],
+					Type:    "User",
+					Ref:     "../Users/" + m.AccountId,
+				})
+			}
+		}
+		groups = append(groups, g)
+	}
+	sort.SliceStable(users, func(i, j int) bool { return users[i].UserName < users[j].UserName })
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].DisplayName < groups[j].DisplayName })
+	return users, groups
+}
+
+func userFromAccount(a identity.AccountProfile, memberships []organize.Membership) User {
+	u := User{
+		Schemas: []string{userSchema},
+		Id:      a.ID,
+		Name: Name{
+			GivenName:  firstNonEmpty(a.FirstNameENG, a.FirstNameTH),
+			FamilyName: firstNonEmpty(a.LastNameENG, a.LastNameTH),
+		},
+		Active: a.StatusCD == "" || strings.EqualFold(a.StatusCD, "active"),
+	}
+	u.Name.Formatted = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+	u.DisplayName = firstNonEmpty(strings.TrimSpace(a.FirstNameTH+" "+a.LastNameTH), u.Name.Formatted)
+
+	seen := map[string]bool{}
+	addEmail := func(email string) {
+		if email != "" && !seen[strings.ToLower(email)] {
+			seen[strings.ToLower(email)] = true
+			u.Emails = append(u.Emails, MultiValue{Value: email, Type: "work", Primary: len(u.Emails) == 0})
+		}
+	}
+	for _, m := range memberships {
+		addEmail(m.Email)
+	}
+	addEmail(a.ThaiEmail1)
+	addEmail(a.ThaiEmail2)
+	for _, e := range a.Email {
+		addEmail(e.Email)
+	}
+	for _, m := range a.Mobile {
+		if m.MobileNumber != "" {
+			u.PhoneNumbers = append(u.PhoneNumbers, MultiValue{Value: m.MobileNumber, Type: "mobile", Primary: len(u.PhoneNumbers) == 0})
+		}
+	}
+	if a.TelephoneNumber != "" {
+		u.PhoneNumbers = append(u.PhoneNumbers, MultiValue{Value: a.TelephoneNumber, Type: "work"})
+	}
+	for _, m := range memberships {
+		if u.ExternalId == "" {
+			u.ExternalId = m.EmployeeId
+		}
+		if u.Title == "" {
+			u.Title = m.Role.Name
+		}
+	}
+	u.UserName = a.ID
+	if len(u.Emails) > 0 {
+		u.UserName = u.Emails[0].Value
+	}
+	if u.DisplayName == "" {
+		u.DisplayName = u.UserName
+	}
+	return u
+}
+
+func (u User) attributes(attr string) []string {
+	var values []string
+	switch attr {
+	case "id":
+		values = []string{u.Id}
+	case "externalid":
+		values = []string{u.ExternalId}
+	case "username":
+		values = []string{u.UserName}
+	case "displayname":
+		values = []string{u.DisplayName}
+	case "title":
+		values = []string{u.Title}
+	case "name.givenname":
+		values = []string{u.Name.GivenName}
+	case "name.familyname":
+		values = []string{u.Name.FamilyName}
+	case "name.formatted":
+		values = []string{u.Name.Formatted}
+	case "active":
+		values = []string{strconv.FormatBool(u.Active)}
+	case "emails", "emails.value":
+		for _, e := range u.Emails {
+			values = append(values, e.Value)
+		}
+	case "phonenumbers", "phonenumbers.value":
+		for _, p := range u.PhoneNumbers {
+			values = append(values, p.Value)
+		}
+	case "groups", "groups.value":
+		for _, g := range u.Groups {
+			values = append(values, g.Value)
+		}
+	}
+	return lower(values)
+}
+
+func (g Group) attributes(attr string) []string {
+	var values []string
+	switch attr {
+	case "id":
+		values = []string{g.Id}
+	case "displayname":
+		values = []string{g.DisplayName}
+	case "members", "members.value":
+		for _, m := range g.Members {
+			values = append(values, m.Value)
+		}
+	}
+	return lower(values)
+}
+
+func lower(values []string) []string {
+	for i, v := range values {
+		values[i] = strings.ToLower(v)
+	}
+	return values
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func queryInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func serviceProviderConfig(pageSize int) interface{} {
+	supported := func(ok bool) map[string]bool { return map[string]bool{"supported": ok} }
+	return map[string]interface{}{
+		"schemas":        []string{configSchema},
+		"patch":          supported(false),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": pageSize},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{
+			{"type": "oauthbearertoken", "name": "OAuth Bearer Token", "description": "Authentication with a bearer token", "primary": true},
+		},
+	}
+}
+
+func resourceTypes() interface{} {
+	types := []map[string]interface{}{
+		{"schemas": []string{resourceTypeSchema}, "id": "User", "name": "User", "endpoint": "/Users", "schema": userSchema},
+		{"schemas": []string{resourceTypeSchema}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": groupSchema},
+	}
+	return ListResponse{
+		Schemas:      []string{listSchema},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, scimType string, detail string) {
+	writeJSON(w, code, Error{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}